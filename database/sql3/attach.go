@@ -0,0 +1,104 @@
+package sql3
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var schemaMatcher = regexp.MustCompile(`^\w+$`)
+
+type attachment struct {
+	schema   string
+	name     string // name given to WithAttach
+	filename string
+}
+
+// WithAttach attaches the database file as schema on every connection of both
+// pools so that queries can join across databases. Tables of the attached
+// database are referenced as "schema.table". See [FS.UpAttached] to migrate
+// the attached database.
+//
+// If the [DB] is in-memory or read-only so is the attached database.
+func WithAttach(schema, filename string) Option {
+	return func(c *config) {
+		c.attach = append(c.attach, attachment{schema: schema, name: filename, filename: filename})
+	}
+}
+
+// validate checks the schema names of the attached databases.
+func (c *config) validate() error {
+	for _, a := range c.attach {
+		if !schemaMatcher.MatchString(a.schema) {
+			return fmt.Errorf("invalid schema name %q", a.schema)
+		}
+	}
+	return nil
+}
+
+// values returns the URI parameters used to open the databases.
+func (c *config) values() url.Values {
+	v := url.Values{}
+	if c.memory {
+		v.Set("vfs", "memdb")
+	}
+	if c.readOnly {
+		v.Set("mode", "ro")
+	}
+	return v
+}
+
+// attachAll attaches the databases to a new connection.
+func (c *config) attachAll(conn *sqlite3.SQLiteConn) error {
+	for _, a := range c.attach {
+		v := c.values()
+		dsn := "file:" + a.filename
+		if len(v) > 0 {
+			dsn += "?" + v.Encode()
+		}
+		if _, err := conn.Exec(`attach database ? as `+a.schema, []driver.Value{dsn}); err != nil {
+			return fmt.Errorf("attach database %s: %w", a.schema, err)
+		}
+		if mode, ok := c.pragma["journal_mode"]; ok {
+			if _, err := conn.Exec(fmt.Sprintf("pragma %s.journal_mode = %s", a.schema, mode), nil); err != nil {
+				return fmt.Errorf("set pragma %s.journal_mode: %w", a.schema, err)
+			}
+		}
+	}
+	return nil
+}
+
+// mkdirAll creates the directories of the attached database files.
+func (c *config) mkdirAll() error {
+	for _, a := range c.attach {
+		if err := os.MkdirAll(filepath.Dir(a.filename), c.dirMode); err != nil {
+			return fmt.Errorf("create directory for database files: %w", err)
+		}
+	}
+	return nil
+}
+
+// UpAttached runs the migrations against the database attached to db as
+// schema, from its current version. Each attached database records its own
+// migration version. The database is opened with the same options as db.
+func (fs *FS) UpAttached(ctx context.Context, db *DB, schema string) error {
+	a, ok := db.attached[schema]
+	if !ok {
+		return fmt.Errorf("no database attached as %q", schema)
+	}
+	adb, err := fs.Up(ctx, a, func(c *config) {
+		*c = *db.config
+		c.attach = nil
+		c.checkpoint = CheckpointPolicy{}
+	})
+	if err != nil {
+		return fmt.Errorf("migrate attached database %s: %w", schema, err)
+	}
+	return adb.Close()
+}
@@ -0,0 +1,95 @@
+package sql3_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_WithAttach(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := sqlFS.Up(context.TODO(), testFilename(t, "main.db"), WithAttach("archive", testFilename(t, "archive.db")))
+		is.NoErr(err) // (sql3.FS).Up
+		t.Cleanup(func() { db.Close() })
+
+		err = sqlFS.UpAttached(context.TODO(), db, "archive")
+		is.NoErr(err) // (sql3.FS).UpAttached
+
+		_, err = db.Exec(context.TODO(), `insert into archive.tests (id, counter) values (?, ?)`, "a", 1)
+		is.NoErr(err) // (sql3.DB).Exec
+		_, err = db.Exec(context.TODO(), `insert into main.tests (id, counter) values (?, ?)`, "a", 2)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		var sum int
+		err = db.QueryRow(context.TODO(), `select m.counter + a.counter from main.tests m join archive.tests a using (id)`).Scan(&sum)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(sum, 3)
+	})
+
+	t.Run("Memory", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := Open("", WithMemory(), WithAttach("archive", "sql3-attach-memory"))
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		err = sqlFS.UpAttached(context.TODO(), db, "archive")
+		is.NoErr(err) // (sql3.FS).UpAttached
+
+		_, err = db.Exec(context.TODO(), `insert into archive.tests (id, counter) values (?, ?)`, "a", 1)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		var n int
+		err = db.QueryRow(context.TODO(), `select count(*) from archive.tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 1)
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		fsys, err := NewFS(fstest.MapFS{
+			"0.up.sql": {Data: []byte(`create table a as select one() as n`)},
+		}, "")
+		is.NoErr(err) // sql3.NewFS
+
+		db, err := Open(testFilename(t, "test.db"),
+			WithFunc("one", func() int { return 1 }, true),
+			WithAttach("archive", testFilename(t, "archive.db")),
+		)
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		// the migration uses a function registered on db
+		err = fsys.UpAttached(context.TODO(), db, "archive")
+		is.NoErr(err) // (sql3.FS).UpAttached
+
+		var n int
+		err = db.QueryRow(context.TODO(), `select n from archive.a`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 1)
+	})
+
+	t.Run("NotAttached", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := Open("", WithMemory())
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		err = sqlFS.UpAttached(context.TODO(), db, "archive")
+		is.True(err != nil) // (sql3.FS).UpAttached
+	})
+
+	t.Run("InvalidSchema", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		_, err := Open("", WithMemory(), WithAttach("a; drop", "x"))
+		is.True(err != nil) // sql3.Open
+	})
+}
@@ -0,0 +1,137 @@
+package sql3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// A BackupOption configures [DB.Backup].
+type BackupOption func(*backupConfig)
+
+type backupConfig struct {
+	pages    int
+	progress func(remaining, total int)
+}
+
+// WithProgress sets a function that is called after each step of a backup
+// with the number of pages remaining and the total number of pages.
+func WithProgress(f func(remaining, total int)) BackupOption {
+	return func(c *backupConfig) { c.progress = f }
+}
+
+// WithStepPages sets the number of pages copied in each step of a backup.
+// A negative value copies the database in a single step.
+func WithStepPages(n int) BackupOption {
+	return func(c *backupConfig) { c.pages = n }
+}
+
+// Backup copies the database to the file dst using the SQLite online backup
+// API. The copy is read from a snapshot on the read pool so the writer is not
+// blocked while the backup runs.
+func (db *DB) Backup(ctx context.Context, dst string, opts ...BackupOption) error {
+	c := &backupConfig{pages: 1024}
+	for _, opt := range opts {
+		opt(c)
+	}
+	dc, err := (&sqlite3.SQLiteDriver{}).Open("file:" + dst)
+	if err != nil {
+		return fmt.Errorf("open backup database: %w", err)
+	}
+	defer dc.Close()
+	return db.backup(ctx, dc.(*sqlite3.SQLiteConn), c)
+}
+
+// backup copies the database to the main schema of dst.
+func (db *DB) backup(ctx context.Context, dst *sqlite3.SQLiteConn, c *backupConfig) error {
+	conn, err := db.rc.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire read connection: %w", err)
+	}
+	defer conn.Close()
+	return conn.Raw(func(dc any) error {
+		cn, err := driverConn(dc)
+		if err != nil {
+			return err
+		}
+		src := cn.SQLiteConn
+		// holding a read transaction keeps the snapshot stable between steps
+		// so writes made during the backup do not cause it to restart
+		if _, err := src.Exec("begin; select 1 from sqlite_schema limit 1", nil); err != nil {
+			return fmt.Errorf("begin read transaction: %w", err)
+		}
+		defer src.Exec("rollback", nil)
+		return backup(ctx, src, dst, c)
+	})
+}
+
+func backup(ctx context.Context, src, dst *sqlite3.SQLiteConn, c *backupConfig) error {
+	b, err := dst.Backup("main", src, "main")
+	if err != nil {
+		return fmt.Errorf("start backup: %w", err)
+	}
+	for done := false; !done; {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(err, b.Close())
+		}
+		if done, err = b.Step(c.pages); err != nil {
+			return errors.Join(fmt.Errorf("backup step: %w", err), b.Close())
+		}
+		if c.progress != nil {
+			c.progress(b.Remaining(), b.PageCount())
+		}
+	}
+	if err := b.Finish(); err != nil {
+		return fmt.Errorf("finish backup: %w", err)
+	}
+	return nil
+}
+
+// Clone copies the database into a new in-memory [DB] opened with the
+// options. See [WithMemory].
+func (db *DB) Clone(ctx context.Context, opts ...Option) (*DB, error) {
+	clone, err := Open("", append(opts, WithMemory())...)
+	if err != nil {
+		return nil, err
+	}
+	// unlike a backup the copy is not in WAL mode, which the memdb VFS
+	// cannot open
+	if _, err := db.rc.ExecContext(ctx, `vacuum into ?`, "file:"+clone.filename+"?vfs=memdb"); err != nil {
+		return nil, errors.Join(fmt.Errorf("clone database: %w", err), clone.Close())
+	}
+	return clone, nil
+}
+
+// BackupTo writes a backup of the database to w. The backup is made in a
+// temporary file which is removed once it has been copied.
+func (db *DB) BackupTo(ctx context.Context, w io.Writer, opts ...BackupOption) (int64, error) {
+	dir, err := os.MkdirTemp("", "sql3-backup-*")
+	if err != nil {
+		return 0, fmt.Errorf("create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "backup.db")
+	if err := db.Backup(ctx, filename, opts...); err != nil {
+		return 0, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, fmt.Errorf("open backup file: %w", err)
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
+
+// VacuumInto writes a compacted copy of the database to the file dst using
+// VACUUM INTO. The file must not already exist.
+func (db *DB) VacuumInto(ctx context.Context, dst string) error {
+	if _, err := db.rc.ExecContext(ctx, `vacuum into ?`, dst); err != nil {
+		return fmt.Errorf("vacuum into: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package sql3_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_DB_Backup(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		for i := range 100 {
+			_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, i, i)
+			is.NoErr(err) // (sql3.DB).Exec
+		}
+
+		var steps int
+		filename := filepath.Join(t.TempDir(), "backup.db")
+		err := db.Backup(context.TODO(), filename, WithStepPages(1), WithProgress(func(remaining, total int) { steps++ }))
+		is.NoErr(err) // (sql3.DB).Backup
+		is.True(steps > 1)
+
+		bk, err := Open(filename)
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { bk.Close() })
+
+		var n int
+		err = bk.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 100)
+	}))
+
+	t.Run("BackupTo", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		var buf bytes.Buffer
+		n, err := db.BackupTo(context.TODO(), &buf)
+		is.NoErr(err) // (sql3.DB).BackupTo
+		is.Equal(int(n), buf.Len())
+		is.True(bytes.HasPrefix(buf.Bytes(), []byte("SQLite format 3\x00")))
+	}))
+
+	t.Run("VacuumInto", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "a", 1)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		filename := filepath.Join(t.TempDir(), "vacuum.db")
+		err = db.VacuumInto(context.TODO(), filename)
+		is.NoErr(err) // (sql3.DB).VacuumInto
+
+		bk, err := Open(filename)
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { bk.Close() })
+
+		var n int
+		err = bk.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 1)
+	}))
+
+	t.Run("Clone", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "a", 1)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		clone, err := db.Clone(context.TODO())
+		is.NoErr(err) // (sql3.DB).Clone
+		t.Cleanup(func() { clone.Close() })
+
+		_, err = clone.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "b", 2)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		var n int
+		err = clone.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 2)
+
+		err = db.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 1)
+	}))
+}
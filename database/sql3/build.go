@@ -0,0 +1,269 @@
+package sql3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// maxVariables is the maximum number of host parameters in a statement, the
+// default SQLITE_MAX_VARIABLE_NUMBER since SQLite 3.32.0.
+const maxVariables = 32766
+
+// Insert inserts v into table and returns the rowid of the new row, or 0 if
+// a key column was inserted.
+//
+// Columns are derived from the fields of T in the same way as [QueryAll]. The
+// "db" tag may be followed by options: "key" marks a field as part of the
+// primary key, "auto" marks a field assigned by the database, such as an
+// INTEGER PRIMARY KEY, which is left out of inserts and "version" marks the
+// version of the row used by [UpdateVersion]. The primary key of a WITHOUT
+// ROWID table must be marked "key".
+func Insert[T any](ctx context.Context, e Executor, table string, v T) (int64, error) {
+	ids, err := InsertAll(ctx, e, table, []T{v})
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// InsertAll inserts vv into table, as with [Insert], using as few statements
+// as SQLite's limit on the number of variables allows. It returns the rowid of
+// each value, which are read with RETURNING so e must also be a [Querier]. No
+// rowids are returned if a key column or the rowid is inserted as the caller
+// already knows the key of each row.
+//
+// If e is a [DB] the statements run in a single transaction.
+func InsertAll[T any](ctx context.Context, e Executor, table string, vv []T) ([]int64, error) {
+	if db, ok := e.(*DB); ok {
+		// the rowids must be read on the write connection
+		var ids []int64
+		err := db.DoTx(ctx, func(ctx context.Context, tx *Tx) (err error) {
+			ids, err = InsertAll(ctx, tx, table, vv)
+			return err
+		})
+		return ids, err
+	}
+	info, err := columnsOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		cols  []column
+		keyed bool
+	)
+	for _, c := range info.columns {
+		if !c.auto {
+			cols = append(cols, c)
+			keyed = keyed || c.key
+		}
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("sql3: no columns to insert for %v", reflect.TypeFor[T]())
+	}
+	q, ok := e.(Querier)
+	if !ok && !keyed {
+		return nil, fmt.Errorf("sql3: %T cannot return the inserted rowids", e)
+	}
+	if !keyed {
+		alias, err := rowidAlias(ctx, q, table)
+		if err != nil {
+			return nil, fmt.Errorf("insert into %s: %w", table, err)
+		}
+		keyed = slices.ContainsFunc(cols, func(c column) bool {
+			return strings.EqualFold(c.name, alias) || isRowid(c.name)
+		})
+	}
+	var ids []int64
+	for chunk := range slices.Chunk(vv, maxVariables/len(cols)) {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "insert into %s (%s) values ", quoteTable(table), columnList(cols, "%s"))
+		args := make([]any, 0, len(chunk)*len(cols))
+		for i, v := range chunk {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(placeholders(len(cols)))
+			args = append(args, values(v, cols)...)
+		}
+		if keyed {
+			if _, err := e.Exec(ctx, sb.String(), args...); err != nil {
+				return nil, fmt.Errorf("insert into %s: %w", table, err)
+			}
+			continue
+		}
+		sb.WriteString(" returning rowid")
+		rowids, err := queryRowIDs(ctx, q, sb.String(), args...)
+		if err != nil {
+			return nil, fmt.Errorf("insert into %s: %w", table, err)
+		}
+		// SQLite returns the rows of an INSERT in the order they are inserted
+		ids = append(ids, rowids...)
+	}
+	return ids, nil
+}
+
+// rowidAlias returns the name of the INTEGER PRIMARY KEY column of table,
+// which is an alias for the rowid, or "" if it has none.
+func rowidAlias(ctx context.Context, q Querier, table string) (string, error) {
+	schema, name := splitTable(table)
+	var alias string
+	err := q.QueryRow(ctx, `select case when count(*) = 1 and upper(max(type)) = 'INTEGER' then max(name) else '' end
+		from pragma_table_info(?, nullif(?, '')) where pk > 0`, name, schema).Scan(&alias)
+	if err != nil {
+		return "", fmt.Errorf("find rowid alias: %w", err)
+	}
+	return alias, nil
+}
+
+// isRowid reports whether name is one of the names SQLite gives the rowid.
+func isRowid(name string) bool {
+	return slices.Contains([]string{"rowid", "oid", "_rowid_"}, strings.ToLower(name))
+}
+
+// queryRowIDs runs the query and returns the rowid of each row.
+func queryRowIDs(ctx context.Context, q Querier, query string, args ...any) ([]int64, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Upsert inserts v into table or, if a row with the same key already exists,
+// updates its other columns. Unlike [Insert] the key columns are inserted even
+// if they are marked "auto" so that they can conflict, unless they are zero
+// in which case the database assigns them.
+func Upsert[T any](ctx context.Context, e Executor, table string, v T) (sql.Result, error) {
+	info, err := columnsOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	keys, rest := splitKeys(info.columns)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("sql3: no key columns for %v", reflect.TypeFor[T]())
+	}
+	rv := reflect.ValueOf(v)
+	var cols []column
+	for _, c := range keys {
+		if !c.auto || !rv.FieldByIndex(c.index).IsZero() {
+			cols = append(cols, c)
+		}
+	}
+	cols = append(cols, rest...)
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("sql3: no columns to insert for %v", reflect.TypeFor[T]())
+	}
+	query := fmt.Sprintf("insert into %s (%s) values %s on conflict (%s) ",
+		quoteTable(table), columnList(cols, "%s"), placeholders(len(cols)), columnList(keys, "%s"))
+	if len(rest) == 0 {
+		query += "do nothing"
+	} else {
+		query += "do update set " + columnList(rest, "%[1]s = excluded.%[1]s")
+	}
+	res, err := e.Exec(ctx, query, values(v, cols)...)
+	if err != nil {
+		return nil, fmt.Errorf("upsert into %s: %w", table, err)
+	}
+	return res, nil
+}
+
+// Update sets the columns of the row in table with the same key as v.
+func Update[T any](ctx context.Context, e Executor, table string, v T) (sql.Result, error) {
+	info, err := columnsOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	keys, rest := splitKeys(info.columns)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("sql3: no key columns for %v", reflect.TypeFor[T]())
+	}
+	if len(rest) == 0 {
+		return nil, fmt.Errorf("sql3: no columns to update for %v", reflect.TypeFor[T]())
+	}
+	query := fmt.Sprintf("update %s set %s where %s",
+		quoteTable(table), columnList(rest, "%s = ?"), strings.ReplaceAll(columnList(keys, "%s = ?"), ", ", " and "))
+	res, err := e.Exec(ctx, query, append(values(v, rest), values(v, keys)...)...)
+	if err != nil {
+		return nil, fmt.Errorf("update %s: %w", table, err)
+	}
+	return res, nil
+}
+
+// columnsOf returns the columns of T, which must be a struct.
+func columnsOf[T any]() (*structInfo, error) {
+	typ := reflect.TypeFor[T]()
+	if !isStruct(typ) {
+		return nil, fmt.Errorf("sql3: %v is not a struct", typ)
+	}
+	return structColumns(typ), nil
+}
+
+// splitKeys returns the key columns and the columns that can be updated.
+func splitKeys(cols []column) (keys, rest []column) {
+	for _, c := range cols {
+		switch {
+		case c.key:
+			keys = append(keys, c)
+		case !c.auto:
+			rest = append(rest, c)
+		}
+	}
+	return keys, rest
+}
+
+// columnList formats each quoted column name with format and joins them with
+// commas.
+func columnList(cols []column, format string) string {
+	ss := make([]string, len(cols))
+	for i, c := range cols {
+		ss[i] = fmt.Sprintf(format, quoteIdent(c.name))
+	}
+	return strings.Join(ss, ", ")
+}
+
+// quoteTable quotes a table name which may be qualified by the name of its
+// schema, such as "main.items".
+func quoteTable(table string) string {
+	schema, name := splitTable(table)
+	if schema == "" {
+		return quoteIdent(name)
+	}
+	return quoteIdent(schema) + "." + quoteIdent(name)
+}
+
+// splitTable splits a table name into the name of its schema, if it is
+// qualified, and the name of the table.
+func splitTable(table string) (schema, name string) {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return schema, name
+	}
+	return "", table
+}
+
+// placeholders returns a parenthesised list of n placeholders.
+func placeholders(n int) string {
+	return "(" + strings.Repeat("?, ", n-1) + "?)"
+}
+
+// values returns the values of the fields of v for cols.
+func values[T any](v T, cols []column) []any {
+	rv := reflect.ValueOf(v)
+	args := make([]any, len(cols))
+	for i, c := range cols {
+		args[i] = rv.FieldByIndex(c.index).Interface()
+	}
+	return args
+}
@@ -0,0 +1,191 @@
+package sql3_test
+
+import (
+	"context"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+type testItem struct {
+	ID    int64  `db:"id,key,auto"`
+	Name  string `db:"name"`
+	Count int    `db:"count"`
+}
+
+const itemsTable = `create table items (id integer primary key, name text not null, count int not null)`
+
+func Test_Insert(t *testing.T) {
+	t.Run("OK", testTable(itemsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		id, err := Insert(context.TODO(), db, "items", testItem{Name: "a", Count: 1})
+		is.NoErr(err) // sql3.Insert
+		is.Equal(id, int64(1))
+
+		item, err := QueryOne[testItem](context.TODO(), db, `select * from items where id = ?`, id)
+		is.NoErr(err) // sql3.QueryOne
+		is.Equal(item, testItem{ID: 1, Name: "a", Count: 1})
+	}))
+
+	t.Run("All", testTable(itemsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		// more rows than fit in a single statement
+		items := make([]testItem, 20000)
+		for i := range items {
+			items[i] = testItem{Name: "item", Count: i}
+		}
+		ids, err := InsertAll(context.TODO(), db, "items", items)
+		is.NoErr(err) // sql3.InsertAll
+		is.Equal(len(ids), len(items))
+
+		for _, i := range []int{0, 16382, 16383, 19999} {
+			n, err := QueryOne[int](context.TODO(), db, `select count from items where id = ?`, ids[i])
+			is.NoErr(err) // sql3.QueryOne
+			is.Equal(n, i)
+		}
+	}))
+
+	t.Run("Trigger", testTable(itemsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := db.Exec(context.TODO(), `create trigger items_copy after insert on items when new.name = 'a'
+			begin insert into items (name, count) values ('copy', 0); end`)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		items := []testItem{{Name: "a", Count: 1}, {Name: "b", Count: 2}, {Name: "a", Count: 3}}
+		ids, err := InsertAll(context.TODO(), db, "items", items)
+		is.NoErr(err) // sql3.InsertAll
+		is.Equal(len(ids), len(items))
+
+		for i, id := range ids {
+			n, err := QueryOne[int](context.TODO(), db, `select count from items where id = ?`, id)
+			is.NoErr(err) // sql3.QueryOne
+			is.Equal(n, items[i].Count)
+		}
+	}))
+
+	t.Run("Key", testTable(itemsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := db.Exec(context.TODO(), `create table tags (name text primary key, count int not null) without rowid`)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		type testTag struct {
+			Name  string `db:"name,key"`
+			Count int    `db:"count"`
+		}
+		ids, err := InsertAll(context.TODO(), db, "tags", []testTag{{"b", 1}, {"a", 2}})
+		is.NoErr(err)         // sql3.InsertAll
+		is.Equal(len(ids), 0) // keys are known to the caller
+	}))
+
+	t.Run("RowID", testTable(itemsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		type testRowID struct {
+			ID    int64  `db:"id"`
+			Name  string `db:"name"`
+			Count int    `db:"count"`
+		}
+		ids, err := InsertAll(context.TODO(), db, "items", []testRowID{{50, "a", 1}, {10, "b", 2}})
+		is.NoErr(err)         // sql3.InsertAll
+		is.Equal(len(ids), 0) // rowids are known to the caller
+
+		n, err := QueryOne[int](context.TODO(), db, `select count from items where id = ?`, 50)
+		is.NoErr(err) // sql3.QueryOne
+		is.Equal(n, 1)
+	}))
+
+	t.Run("Schema", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := Open(testFilename(t, "test.db"), WithAttach("archive", testFilename(t, "archive.db")))
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		_, err = db.Exec(context.TODO(), `create table archive.items (id integer primary key, name text not null, count int not null)`)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		id, err := Insert(context.TODO(), db, "archive.items", testItem{Name: "a", Count: 1})
+		is.NoErr(err) // sql3.Insert
+		is.Equal(id, int64(1))
+
+		_, err = Upsert(context.TODO(), db, "archive.items", testItem{ID: id, Name: "b", Count: 2})
+		is.NoErr(err) // sql3.Upsert
+
+		_, err = Update(context.TODO(), db, "archive.items", testItem{ID: id, Name: "c", Count: 3})
+		is.NoErr(err) // sql3.Update
+
+		items, err := QueryAll[testItem](context.TODO(), db, `select * from archive.items`)
+		is.NoErr(err) // sql3.QueryAll
+		is.Equal(items, []testItem{{ID: 1, Name: "c", Count: 3}})
+	})
+}
+
+func Test_Upsert(t *testing.T) {
+	t.Run("OK", testTable(itemsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := Upsert(context.TODO(), db, "items", testItem{ID: 1, Name: "a", Count: 1})
+		is.NoErr(err) // sql3.Upsert
+		_, err = Upsert(context.TODO(), db, "items", testItem{ID: 1, Name: "b", Count: 2})
+		is.NoErr(err) // sql3.Upsert
+
+		items, err := QueryAll[testItem](context.TODO(), db, `select * from items`)
+		is.NoErr(err) // sql3.QueryAll
+		is.Equal(items, []testItem{{ID: 1, Name: "b", Count: 2}})
+	}))
+
+	t.Run("Auto", testTable(itemsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := Upsert(context.TODO(), db, "items", testItem{Name: "a", Count: 1})
+		is.NoErr(err) // sql3.Upsert
+		_, err = Upsert(context.TODO(), db, "items", testItem{Name: "b", Count: 2})
+		is.NoErr(err) // sql3.Upsert
+
+		items, err := QueryAll[testItem](context.TODO(), db, `select * from items order by id`)
+		is.NoErr(err) // sql3.QueryAll
+		is.Equal(items, []testItem{{ID: 1, Name: "a", Count: 1}, {ID: 2, Name: "b", Count: 2}})
+	}))
+}
+
+func Test_Update(t *testing.T) {
+	t.Run("OK", testTable(itemsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		id, err := Insert(context.TODO(), db, "items", testItem{Name: "a", Count: 1})
+		is.NoErr(err) // sql3.Insert
+
+		res, err := Update(context.TODO(), db, "items", testItem{ID: id, Name: "a", Count: 2})
+		is.NoErr(err) // sql3.Update
+		n, _ := res.RowsAffected()
+		is.Equal(n, int64(1))
+
+		count, err := QueryOne[int](context.TODO(), db, `select count from items where id = ?`, id)
+		is.NoErr(err) // sql3.QueryOne
+		is.Equal(count, 2)
+	}))
+
+	t.Run("NoKey", testTable(itemsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := Update(context.TODO(), db, "tests", testRow{ID: "a"})
+		is.True(err != nil) // sql3.Update
+	}))
+}
+
+// testTable returns a test which creates a table with ddl before running f.
+func testTable(ddl string, f func(*DB)) func(*testing.T) {
+	return func(t *testing.T) {
+		testRoundTrip(func(db *DB) {
+			if _, err := db.Exec(context.TODO(), ddl); err != nil {
+				t.Fatalf("(sql3.DB).Exec: %v", err)
+			}
+			f(db)
+		})(t)
+	}
+}
@@ -0,0 +1,178 @@
+package sql3
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ChangeOp is the operation that changed a row.
+type ChangeOp int
+
+const (
+	ChangeInsert ChangeOp = sqlite3.SQLITE_INSERT
+	ChangeUpdate ChangeOp = sqlite3.SQLITE_UPDATE
+	ChangeDelete ChangeOp = sqlite3.SQLITE_DELETE
+)
+
+func (op ChangeOp) String() string {
+	switch op {
+	case ChangeInsert:
+		return "INSERT"
+	case ChangeUpdate:
+		return "UPDATE"
+	case ChangeDelete:
+		return "DELETE"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// A Change describes a row that was changed by a committed transaction.
+type Change struct {
+	Op       ChangeOp
+	Database string // Schema name, "main" unless the database is attached.
+	Table    string
+	RowID    int64
+}
+
+// A Subscription receives the changes made to a [DB].
+type Subscription struct {
+	c       chan Change
+	tables  []string
+	dropped atomic.Uint64
+	feed    *changeFeed
+	once    sync.Once
+}
+
+// C returns the channel on which changes are delivered. It is closed when the
+// subscription is closed.
+func (s *Subscription) C() <-chan Change { return s.c }
+
+// Dropped returns the number of changes that were discarded because the
+// buffer of the subscription was full.
+func (s *Subscription) Dropped() uint64 { return s.dropped.Load() }
+
+// Close stops delivery of changes and closes the channel.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.feed.mu.Lock()
+		delete(s.feed.subs, s)
+		s.feed.active.Add(-1)
+		close(s.c)
+		s.feed.mu.Unlock()
+	})
+}
+
+// Subscribe returns a [Subscription] to the rows changed in tables, or every
+// table if none are given. Changes are delivered once the transaction that
+// made them has committed.
+//
+// The channel of the subscription holds up to buffer changes. The writer never
+// waits for a subscriber, if the buffer is full the change is dropped and
+// counted by [Subscription.Dropped].
+func (db *DB) Subscribe(buffer int, tables ...string) *Subscription {
+	s := &Subscription{c: make(chan Change, buffer), tables: tables, feed: db.feed}
+	db.feed.mu.Lock()
+	db.feed.subs[s] = struct{}{}
+	db.feed.active.Add(1)
+	db.feed.mu.Unlock()
+	return s
+}
+
+// changeFeed collects the changes made on the write connections and delivers
+// them to subscribers.
+type changeFeed struct {
+	active atomic.Int32 // number of subscribers
+	mu     sync.Mutex
+	ready  []Change // committed but not yet delivered
+	subs   map[*Subscription]struct{}
+}
+
+func newChangeFeed() *changeFeed {
+	return &changeFeed{subs: make(map[*Subscription]struct{})}
+}
+
+// changeLog holds the changes made by the open transaction of a write
+// connection.
+type changeLog struct {
+	feed      *changeFeed
+	pending   []Change // made by the open transaction
+	committed []Change // made by a transaction which is committing
+}
+
+// settle makes the changes of a committing transaction ready for delivery if
+// it committed, otherwise they are discarded. The commit hook runs before
+// the commit has succeeded so the connection calls this once it knows.
+func (l *changeLog) settle(ok bool) {
+	if ok && len(l.committed) > 0 {
+		l.feed.mu.Lock()
+		l.feed.ready = append(l.feed.ready, l.committed...)
+		l.feed.mu.Unlock()
+	}
+	l.committed = nil
+}
+
+// savepoint returns a mark of the changes made before a savepoint.
+func (l *changeLog) savepoint() int { return len(l.pending) }
+
+// rollbackTo discards the changes made since the savepoint was marked. The
+// update hook is not told when a savepoint is rolled back so [Tx] calls this.
+func (l *changeLog) rollbackTo(mark int) {
+	if mark < len(l.pending) {
+		l.pending = l.pending[:mark]
+	}
+}
+
+// register installs the hooks that record changes on a write connection and
+// returns its changeLog.
+func (f *changeFeed) register(conn *sqlite3.SQLiteConn) *changeLog {
+	l := &changeLog{feed: f}
+	conn.RegisterUpdateHook(func(op int, db, table string, rowid int64) {
+		if f.active.Load() > 0 {
+			l.pending = append(l.pending, Change{Op: ChangeOp(op), Database: db, Table: table, RowID: rowid})
+		}
+	})
+	conn.RegisterCommitHook(func() int {
+		l.committed, l.pending = l.pending, nil
+		return 0
+	})
+	conn.RegisterRollbackHook(func() {
+		l.pending, l.committed = nil, nil
+	})
+	return l
+}
+
+// flush delivers the changes of committed transactions.
+func (f *changeFeed) flush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.ready {
+		for s := range f.subs {
+			if len(s.tables) > 0 && !slices.Contains(s.tables, c.Table) {
+				continue
+			}
+			select {
+			case s.c <- c:
+			default:
+				s.dropped.Add(1)
+			}
+		}
+	}
+	f.ready = nil
+}
+
+// close closes every subscription.
+func (f *changeFeed) close() {
+	f.mu.Lock()
+	subs := make([]*Subscription, 0, len(f.subs))
+	for s := range f.subs {
+		subs = append(subs, s)
+	}
+	f.mu.Unlock()
+	for _, s := range subs {
+		s.Close()
+	}
+}
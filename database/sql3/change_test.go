@@ -0,0 +1,83 @@
+package sql3_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_DB_Subscribe(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		s := db.Subscribe(10, "tests")
+		t.Cleanup(s.Close)
+
+		err := db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			if _, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "a", 1); err != nil {
+				return err
+			}
+			is.Equal(len(s.C()), 0) // not delivered before commit
+			_ = tx.DoTx(ctx, func(ctx context.Context, tx *Tx) error {
+				if _, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "b", 2); err != nil {
+					return err
+				}
+				return errors.New("rollback")
+			})
+			return nil
+		})
+		is.NoErr(err) // (sql3.DB).DoTx
+
+		_, err = db.Exec(context.TODO(), `delete from tests where id = ?`, "a")
+		is.NoErr(err) // (sql3.DB).Exec
+
+		is.Equal(len(s.C()), 2)
+		c := <-s.C()
+		is.Equal(c.Op, ChangeInsert)
+		is.Equal(c.Table, "tests")
+		c = <-s.C()
+		is.Equal(c.Op, ChangeDelete)
+	}))
+
+	t.Run("Dropped", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		s := db.Subscribe(1)
+		t.Cleanup(s.Close)
+
+		for i := range 3 {
+			_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, i, i)
+			is.NoErr(err) // (sql3.DB).Exec
+		}
+		is.Equal(len(s.C()), 1)
+		is.Equal(s.Dropped(), uint64(2))
+	}))
+
+	t.Run("Migrations", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		fsys, err := NewFS(fstest.MapFS{
+			"0.up.sql": {Data: []byte(`create table a (id int)`)},
+			"1.up.sql": {Data: []byte(`insert into a (id) values (1)`)},
+		}, "")
+		is.NoErr(err) // sql3.NewFS
+
+		db, err := Open(testFilename(t, "test.db"))
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		err = fsys.To(context.TODO(), db, "0")
+		is.NoErr(err) // (sql3.FS).To
+
+		s := db.Subscribe(10, "a")
+		t.Cleanup(s.Close)
+
+		err = fsys.To(context.TODO(), db, "1")
+		is.NoErr(err) // (sql3.FS).To
+		is.Equal(len(s.C()), 1)
+	})
+}
@@ -0,0 +1,63 @@
+package sql3
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultCheckpointPolicy is the [CheckpointPolicy] used by a [DB] returned
+// from [Open]. A PASSIVE checkpoint never waits for readers or writers.
+var DefaultCheckpointPolicy = CheckpointPolicy{
+	Interval:   time.Minute,
+	Mode:       CheckpointPassive,
+	MinWALSize: 4 << 20,
+}
+
+// CheckpointPolicy describes how a [DB] checkpoints its WAL in the background.
+//
+// Checkpoints run on the single write connection so statements on the write
+// pool wait for them to finish. FULL, RESTART and TRUNCATE checkpoints also
+// wait, up to busy_timeout, for readers to finish so writers can be blocked
+// for as long.
+type CheckpointPolicy struct {
+	// Interval between checkpoints. A value of zero disables the checkpointer.
+	Interval time.Duration
+	// Mode of each checkpoint. Defaults to [CheckpointPassive].
+	Mode CheckpointMode
+	// MinWALSize skips checkpoints while the frames of the WAL which have
+	// not been checkpointed take up fewer than this many bytes. The WAL file
+	// itself only shrinks after a TRUNCATE checkpoint.
+	MinWALSize int64
+	// OnCheckpoint, if set, is called with the result of each checkpoint.
+	OnCheckpoint func(CheckpointResult, error)
+}
+
+// checkpointer runs checkpoints until ctx is done.
+func (db *DB) checkpointer(ctx context.Context, p CheckpointPolicy) {
+	if p.Mode == "" {
+		p.Mode = CheckpointPassive
+	}
+	t := time.NewTicker(p.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if p.MinWALSize > 0 {
+			n, err := db.walBacklog()
+			if err == nil && n < p.MinWALSize {
+				continue
+			}
+		}
+		c, err := db.Checkpoint(ctx, p.Mode)
+		if ctx.Err() != nil {
+			// closing the DB is not a failed checkpoint
+			return
+		}
+		if p.OnCheckpoint != nil {
+			p.OnCheckpoint(c, err)
+		}
+	}
+}
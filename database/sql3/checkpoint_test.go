@@ -0,0 +1,76 @@
+package sql3_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_WithCheckpoint(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		results := make(chan CheckpointResult, 1)
+		db, err := sqlFS.Up(context.TODO(), testFilename(t, "test.db"), WithCheckpoint(CheckpointPolicy{
+			Interval:   10 * time.Millisecond,
+			Mode:       CheckpointTruncate,
+			MinWALSize: 1,
+			OnCheckpoint: func(c CheckpointResult, err error) {
+				is.NoErr(err) // (sql3.DB).Checkpoint
+				select {
+				case results <- c:
+				default:
+				}
+			},
+		}))
+		is.NoErr(err) // (sql3.FS).Up
+
+		_, err = db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "a", 1)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		select {
+		case c := <-results:
+			is.Equal(c.Mode, CheckpointTruncate)
+		case <-time.After(5 * time.Second):
+			t.Fatal("checkpoint did not run")
+		}
+		is.NoErr(db.Close()) // (sql3.DB).Close
+	})
+	t.Run("Idle", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		var n atomic.Int64
+		db, err := sqlFS.Up(context.TODO(), testFilename(t, "test.db"), WithCheckpoint(CheckpointPolicy{
+			Interval:   10 * time.Millisecond,
+			MinWALSize: 128 << 10,
+			OnCheckpoint: func(c CheckpointResult, err error) {
+				is.NoErr(err) // (sql3.DB).Checkpoint
+				n.Add(1)
+			},
+		}))
+		is.NoErr(err) // (sql3.FS).Up
+		t.Cleanup(func() { db.Close() })
+
+		_, err = db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "a", 1)
+		is.NoErr(err) // (sql3.DB).Exec
+		time.Sleep(50 * time.Millisecond)
+		is.Equal(n.Load(), int64(0)) // below the threshold
+
+		_, err = db.Exec(context.TODO(), `insert into tests (id, counter) values (hex(randomblob(256 << 10)), ?)`, 2)
+		is.NoErr(err) // (sql3.DB).Exec
+		for i := 0; n.Load() == 0; i++ {
+			if i == 500 {
+				t.Fatal("checkpoint did not run")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		// the WAL file keeps its size but every frame has been checkpointed
+		time.Sleep(50 * time.Millisecond)
+		is.Equal(n.Load(), int64(1))
+	})
+}
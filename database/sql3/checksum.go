@@ -0,0 +1,184 @@
+package sql3
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// checksumsTable records the checksum of each applied up migration.
+const checksumsTable = "migration_checksums"
+
+// ErrChecksumMismatch is returned by [FS.Up] when a migration that has already
+// been applied has since changed.
+var ErrChecksumMismatch = errors.New("sql3: applied migration has changed")
+
+// OnDrift returns a copy of fs which calls f, instead of failing [FS.Up], when
+// a migration that has already been applied has since changed.
+func (fs *FS) OnDrift(f func(err error)) *FS {
+	c := *fs
+	c.onDrift = f
+	return &c
+}
+
+// record is called by the [migrate.Migrator] after migrating to version. The
+// checksums of versions that are no longer applied are removed.
+func (fs *FS) record(ctx context.Context, tx *sql.Tx, version string) error {
+	if err := createChecksumsTable(ctx, tx); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `delete from `+checksumsTable+` where version > ?`, version); err != nil {
+		return fmt.Errorf("remove migration checksums: %w", err)
+	}
+	if version == "" {
+		return nil
+	}
+	sum, err := checksum(fs.fsys, version+".up.sql")
+	if err != nil {
+		return err
+	}
+	// migrating down keeps the checksum of the version that was applied
+	_, err = tx.ExecContext(ctx, `insert into `+checksumsTable+` (version, checksum) values (?, ?) on conflict do nothing`, version, sum)
+	if err != nil {
+		return fmt.Errorf("record migration checksum: %w", err)
+	}
+	return nil
+}
+
+// verify checks the applied up migrations have not changed. Migrations applied
+// before checksums were recorded have their current checksum recorded.
+func (fs *FS) verify(ctx context.Context, db *DB) error {
+	current, err := fs.Version(ctx, db)
+	if err != nil || current == "" {
+		return err
+	}
+	names, err := filenames(fs.fsys, upMatcher)
+	if err != nil {
+		return err
+	}
+	tx, err := db.wc.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := createChecksumsTable(ctx, tx); err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range names {
+		v := version(upMatcher, name)
+		if v > current {
+			break
+		}
+		sum, err := checksum(fs.fsys, name)
+		if err != nil {
+			return err
+		}
+		var applied string
+		err = tx.QueryRowContext(ctx, `select checksum from `+checksumsTable+` where version = ?`, v).Scan(&applied)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if _, err := tx.ExecContext(ctx, `insert into `+checksumsTable+` (version, checksum) values (?, ?)`, v, sum); err != nil {
+				return fmt.Errorf("record migration checksum: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("get migration checksum: %w", err)
+		case applied != sum:
+			err := fmt.Errorf("%w: %s", ErrChecksumMismatch, name)
+			if fs.onDrift == nil {
+				errs = append(errs, err)
+				continue
+			}
+			fs.onDrift(err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration checksums: %w", err)
+	}
+	return nil
+}
+
+func createChecksumsTable(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `create table if not exists `+checksumsTable+` (version text not null primary key, checksum text not null)`)
+	if err != nil {
+		return fmt.Errorf("create migration checksums table: %w", err)
+	}
+	return nil
+}
+
+func checksum(fsys fs.FS, name string) (string, error) {
+	p, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", fmt.Errorf("read migration file %s: %w", name, err)
+	}
+	sum := sha256.Sum256(p)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+var sqlMatcher = regexp.MustCompile(`^([\w-]+)\.(up|down)\.sql$`)
+
+// Validate checks the migration files are named "<version>.up.sql" or
+// "<version>.down.sql", that every up migration has a matching down migration
+// and that numeric versions have no gaps and sort in numeric order.
+func (fs *FS) Validate() error {
+	return validate(fs.fsys)
+}
+
+func validate(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return fmt.Errorf("read migrations directory: %w", err)
+	}
+	var (
+		errs     []error
+		versions []string
+		downs    = make(map[string]bool)
+	)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		m := sqlMatcher.FindStringSubmatch(name)
+		switch {
+		case m == nil:
+			errs = append(errs, fmt.Errorf("invalid migration file name %q", name))
+		case m[2] == "up":
+			versions = append(versions, m[1])
+		default:
+			downs[m[1]] = true
+		}
+	}
+	for _, v := range versions {
+		if !downs[v] {
+			errs = append(errs, fmt.Errorf("missing down migration for version %s", v))
+		}
+		delete(downs, v)
+	}
+	for _, v := range slices.Sorted(maps.Keys(downs)) {
+		errs = append(errs, fmt.Errorf("missing up migration for version %s", v))
+	}
+	// the files are applied in alphabetical order
+	for i := 1; i < len(versions); i++ {
+		prev, err1 := strconv.Atoi(versions[i-1])
+		next, err2 := strconv.Atoi(versions[i])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if next != prev+1 {
+			errs = append(errs, fmt.Errorf("migration version %s follows %s", versions[i], versions[i-1]))
+		}
+	}
+	return errors.Join(errs...)
+}
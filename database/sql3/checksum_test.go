@@ -0,0 +1,67 @@
+package sql3_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_FS_Validate(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		is.NoErr(sqlFS.Validate()) // (sql3.FS).Validate
+	})
+
+	t.Run("Err", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		fsys, err := NewFS(fstest.MapFS{
+			"0.up.sql":   {Data: []byte(`create table a (id int)`)},
+			"0.down.sql": {Data: []byte(`drop table a`)},
+			"2.up.sql":   {Data: []byte(`create table b (id int)`)},
+			"3.down.sql": {Data: []byte(`drop table c`)},
+			"4.sql":      {Data: []byte(`create table d (id int)`)},
+		}, "")
+		is.NoErr(err) // sql3.NewFS
+
+		err = fsys.Validate()
+		is.Equal(err.Error(), `invalid migration file name "4.sql"
+missing down migration for version 2
+missing up migration for version 3
+migration version 2 follows 0`)
+	})
+}
+
+func Test_FS_Up(t *testing.T) {
+	t.Run("Drift", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		mfs := fstest.MapFS{
+			"0.up.sql": {Data: []byte(`create table a (id int)`)},
+		}
+		fsys, err := NewFS(mfs, "")
+		is.NoErr(err) // sql3.NewFS
+
+		filename := testFilename(t, "test.db")
+		db, err := fsys.Up(context.TODO(), filename)
+		is.NoErr(err) // (sql3.FS).Up
+		is.NoErr(db.Close())
+
+		mfs["0.up.sql"] = &fstest.MapFile{Data: []byte(`create table a (id int, name text)`)}
+		_, err = fsys.Up(context.TODO(), filename)
+		is.Err(err, ErrChecksumMismatch) // (sql3.FS).Up
+
+		var drift error
+		db, err = fsys.OnDrift(func(err error) { drift = err }).Up(context.TODO(), filename)
+		is.NoErr(err) // (sql3.FS).Up
+		is.NoErr(db.Close())
+		is.Err(drift, ErrChecksumMismatch)
+
+		_, err = fsys.Up(context.TODO(), filename)
+		is.Err(err, ErrChecksumMismatch) // (sql3.FS).Up is not changed by OnDrift
+	})
+}
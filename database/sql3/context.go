@@ -0,0 +1,41 @@
+package sql3
+
+import "context"
+
+type contextKey struct{ string }
+
+func (k *contextKey) String() string { return "sql3: context value " + k.string }
+
+var (
+	TxContextKey = &contextKey{"tx"}
+)
+
+// NewContext returns a copy of ctx that carries tx.
+func NewContext(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, TxContextKey, tx)
+}
+
+// TxFromContext returns the [Tx] carried by ctx, if any. A transaction that
+// has been committed or rolled back is ignored.
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(TxContextKey).(*Tx)
+	return tx, ok && tx != nil && !tx.done
+}
+
+// Executor returns the [Tx] carried by ctx or db if there is none, so that
+// writes take part in the transaction of the caller.
+func (db *DB) Executor(ctx context.Context) Executor {
+	if tx, ok := TxFromContext(ctx); ok && tx.db == db {
+		return tx
+	}
+	return db
+}
+
+// Querier returns the [Tx] carried by ctx or db if there is none, so that
+// reads see the uncommitted writes of the transaction of the caller.
+func (db *DB) Querier(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok && tx.db == db {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,47 @@
+package sql3_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_DB_Executor(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		insert := func(ctx context.Context, id string) error {
+			_, err := db.Executor(ctx).Exec(ctx, `insert into tests (id, counter) values (?, ?)`, id, 1)
+			return err
+		}
+		count := func(ctx context.Context) (n int) {
+			err := db.Querier(ctx).QueryRow(ctx, `select count(*) from tests`).Scan(&n)
+			is.NoErr(err) // (sql3.Querier).QueryRow
+			return n
+		}
+
+		errAbort := errors.New("abort")
+		err := db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			if err := insert(ctx, "a"); err != nil {
+				return err
+			}
+			is.Equal(count(ctx), 1) // sees uncommitted write
+			is.Equal(count(context.TODO()), 0)
+
+			// joins the outer transaction instead of deadlocking
+			err := db.DoTx(ctx, func(ctx context.Context, tx *Tx) error {
+				if err := insert(ctx, "b"); err != nil {
+					return err
+				}
+				return errAbort
+			})
+			is.Err(err, errAbort) // (sql3.DB).DoTx
+			return nil
+		})
+		is.NoErr(err) // (sql3.DB).DoTx
+		is.Equal(count(context.TODO()), 1)
+	}))
+}
@@ -3,16 +3,17 @@ package sql3
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"runtime"
-	"strings"
+	"slices"
+	"sync"
+	"sync/atomic"
 
-	"github.com/maragudk/migrate"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 type Executor interface {
@@ -24,116 +25,460 @@ type Querier interface {
 	QueryRow(ctx context.Context, query string, args ...any) *sql.Row
 }
 
-const driverName = "sqlite3"
-
 // DB
 type DB struct {
-	wc *sql.DB
-	rc *sql.DB
+	wc     *sql.DB
+	rc     *sql.DB
+	wcache *stmtCache
+	rcache *stmtCache
+	retry  RetryPolicy
+	hooks  hooks
+	feed   *changeFeed
+
+	filename   string
+	memory     []*sqlite3.SQLiteConn // keep the in-memory databases alive
+	config     *config               // options the database was opened with
+	attached   map[string]string     // filename by schema
+	checkpoint atomic.Pointer[CheckpointResult]
+
+	stop func()         // stops background work
+	wg   sync.WaitGroup // background work
 }
 
 // Close closes the database and prevents new queries from starting.
 func (db *DB) Close() error {
-	return errors.Join(db.wc.Close(), db.rc.Close())
+	db.stop()
+	db.wg.Wait()
+	db.feed.close()
+	var errs []error
+	for _, c := range []*stmtCache{db.wcache, db.rcache} {
+		if c != nil {
+			errs = append(errs, c.Close())
+		}
+	}
+	errs = append(errs, db.wc.Close(), db.rc.Close())
+	return errors.Join(append(errs, closeMemory(db.memory))...)
 }
 
 // Exec executes a query without returning any rows. The args are for any placeholder parameters in the query.
-func (db *DB) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return db.wc.ExecContext(ctx, query, args...)
+func (db *DB) Exec(ctx context.Context, query string, args ...any) (res sql.Result, err error) {
+	e := &QueryEvent{Pool: PoolWrite, Op: OpExec, Query: query, Args: args}
+	err = db.hooks.do(ctx, e, func(ctx context.Context) error {
+		return db.retry.do(ctx, func() error {
+			if db.wcache == nil {
+				res, err = db.wc.ExecContext(ctx, query, args...)
+				return err
+			}
+			stmt, release, err := db.wcache.prepare(ctx, query)
+			if err != nil {
+				return err
+			}
+			defer release()
+			res, err = stmt.ExecContext(ctx, args...)
+			return err
+		})
+	})
+	if err == nil {
+		db.feed.flush()
+	}
+	return res, err
 }
 
 // Query executes a query that returns rows, typically a SELECT. The args are for any placeholder parameters in the query.
-func (db *DB) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	return db.rc.QueryContext(ctx, query, args...)
+func (db *DB) Query(ctx context.Context, query string, args ...any) (rows *sql.Rows, err error) {
+	e := &QueryEvent{Pool: PoolRead, Op: OpQuery, Query: query, Args: args}
+	err = db.hooks.do(ctx, e, func(ctx context.Context) error {
+		if db.rcache == nil {
+			rows, err = db.rc.QueryContext(ctx, query, args...)
+			return err
+		}
+		stmt, release, err := db.rcache.prepare(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer release()
+		rows, err = stmt.QueryContext(ctx, args...)
+		return err
+	})
+	return rows, err
 }
 
 // QueryRow executes a query that is expected to return at most one row.
-func (db *DB) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
-	return db.rc.QueryRowContext(ctx, query, args...)
+func (db *DB) QueryRow(ctx context.Context, query string, args ...any) (row *sql.Row) {
+	e := &QueryEvent{Pool: PoolRead, Op: OpQuery, Query: query, Args: args}
+	db.hooks.do(ctx, e, func(ctx context.Context) error {
+		if db.rcache != nil {
+			if stmt, release, err := db.rcache.prepare(ctx, query); err == nil {
+				defer release()
+				row = stmt.QueryRowContext(ctx, args...)
+				return row.Err()
+			}
+		}
+		// any error preparing the statement is returned by the row
+		row = db.rc.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
 }
 
 // Tx
 func (db *DB) Tx(ctx context.Context) (*Tx, error) {
-	tx, err := db.wc.BeginTx(ctx, &sql.TxOptions{})
+	var (
+		conn *sql.Conn
+		log  *changeLog
+		tx   *sql.Tx
+	)
+	err := db.hooks.do(ctx, &QueryEvent{Pool: PoolWrite, Op: OpBegin}, func(ctx context.Context) (err error) {
+		if conn, err = db.wc.Conn(ctx); err != nil {
+			return err
+		}
+		// the changes made by the transaction are recorded on its connection
+		if err = conn.Raw(func(dc any) error {
+			c, err := driverConn(dc)
+			if err != nil {
+				return err
+			}
+			log = c.log
+			return nil
+		}); err != nil {
+			return errors.Join(err, conn.Close())
+		}
+		if tx, err = conn.BeginTx(ctx, &sql.TxOptions{}); err != nil {
+			return errors.Join(err, conn.Close())
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("begin transaction: %w", err)
 	}
-	return &Tx{tx: tx}, nil
+	return &Tx{txn: db.txn(ctx, tx, PoolWrite), conn: conn, log: log, n: new(int)}, nil
 }
 
-// DoTx begins a transaction.
+// DoTx begins a transaction, runs f and commits if f returns nil.
+// Otherwise the transaction is rolled back.
 //
-// FIXME issue with transaction already been committed
+// The context passed to f carries the transaction, see [NewContext]. If ctx
+// already carries a transaction f is run in a nested transaction instead.
+// f may also call [Tx.DoTx] to run a nested transaction. If the transaction fails
+// because the database is busy or locked f is run again in a new transaction,
+// as described by the [RetryPolicy] of the DB.
 func (db *DB) DoTx(ctx context.Context, f func(context.Context, *Tx) error) error {
-	tx, err := db.Tx(ctx)
+	if tx, ok := TxFromContext(ctx); ok && tx.db == db {
+		// join the transaction of the caller
+		return tx.DoTx(ctx, f)
+	}
+	return db.retry.do(ctx, func() error {
+		tx, err := db.Tx(ctx)
+		if err != nil {
+			return err
+		}
+		return tx.do(ctx, f)
+	})
+}
+
+// ReadTx begins a read-only transaction on the read pool. Queries made within
+// the transaction see a consistent snapshot of the database without blocking
+// the writer. Statements that would change the database fail.
+func (db *DB) ReadTx(ctx context.Context) (*ReadTx, error) {
+	var (
+		conn *sql.Conn
+		tx   *sql.Tx
+	)
+	err := db.hooks.do(ctx, &QueryEvent{Pool: PoolRead, Op: OpBegin}, func(ctx context.Context) (err error) {
+		if conn, err = db.rc.Conn(ctx); err != nil {
+			return err
+		}
+		// the driver ignores [sql.TxOptions.ReadOnly]
+		if _, err = conn.ExecContext(ctx, "pragma query_only = 1"); err != nil {
+			return errors.Join(err, conn.Close())
+		}
+		if tx, err = conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true}); err != nil {
+			return errors.Join(err, releaseQueryOnly(conn))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("begin read transaction: %w", err)
+	}
+	return &ReadTx{txn: db.txn(ctx, tx, PoolRead), conn: conn}, nil
+}
+
+// DoReadTx begins a read-only transaction and runs f. The transaction is
+// always rolled back after f returns.
+func (db *DB) DoReadTx(ctx context.Context, f func(context.Context, *ReadTx) error) error {
+	tx, err := db.ReadTx(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
-	if err = f(ctx, tx); err != nil {
-		return fmt.Errorf("run transaction: %w", err)
-	}
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("commit read/write transactions: %w", err)
+	if err := f(ctx, tx); err != nil {
+		return fmt.Errorf("run read transaction: %w", err)
 	}
 	return nil
 }
 
 // Open a new [DB] connection.
-func Open(filename string) (*DB, error) {
-	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
-		return nil, fmt.Errorf("create directory for database files: %w", err)
+func Open(filename string, opts ...Option) (*DB, error) {
+	c := newConfig(opts...)
+	if err := c.validate(); err != nil {
+		return nil, err
 	}
-	dsn := fmt.Sprintf("file:%s?%s", filename, pragma())
-	wc, err := sql.Open(driverName, dsn)
-	if err != nil {
-		return nil, fmt.Errorf("open write pool: %w", err)
+	var memory []*sqlite3.SQLiteConn
+	if c.memory {
+		if filename == "" {
+			filename = fmt.Sprintf("sql3-memory-%d", memoryID.Add(1))
+		}
+		var err error
+		if filename, memory, err = c.openMemory(filename); err != nil {
+			return nil, err
+		}
+	} else if !c.readOnly {
+		if err := os.MkdirAll(filepath.Dir(filename), c.dirMode); err != nil {
+			return nil, fmt.Errorf("create directory for database files: %w", err)
+		}
+		if err := c.mkdirAll(); err != nil {
+			return nil, err
+		}
 	}
-	wc.SetMaxOpenConns(1)
-	rc, err := sql.Open(driverName, dsn)
-	if err != nil {
-		return nil, fmt.Errorf("open read pool: %w", err)
+	feed := newChangeFeed()
+	wc := sql.OpenDB(c.connector(filename, "immediate", feed))
+	wc.SetMaxOpenConns(c.writeConns)
+	// readers should not take the write lock when beginning a transaction
+	rc := sql.OpenDB(c.connector(filename, "deferred", nil))
+	rc.SetMaxOpenConns(c.readConns)
+	for _, p := range []*sql.DB{wc, rc} {
+		p.SetConnMaxLifetime(c.connMaxLifetime)
+		p.SetConnMaxIdleTime(c.connMaxIdleTime)
+	}
+	db := &DB{wc: wc, rc: rc, retry: c.retry, hooks: c.hooks, feed: feed, filename: filename, memory: memory, config: c}
+	db.attached = make(map[string]string, len(c.attach))
+	for _, a := range c.attach {
+		db.attached[a.schema] = a.name
 	}
-	rc.SetMaxOpenConns(max(4, runtime.NumCPU()))
-	return &DB{wc: wc, rc: rc}, nil
+	ctx, cancel := context.WithCancel(context.Background())
+	db.stop = cancel
+	if c.stmtCacheSize > 0 {
+		db.wcache = newStmtCache(wc, c.stmtCacheSize)
+		db.rcache = newStmtCache(rc, c.stmtCacheSize)
+	}
+	if p := c.checkpoint; p.Interval > 0 && !c.memory && !c.readOnly {
+		db.wg.Add(1)
+		go func() {
+			defer db.wg.Done()
+			db.checkpointer(ctx, p)
+		}()
+	}
+	return db, nil
 }
 
 // Tx
 type Tx struct {
-	tx *sql.Tx
+	txn
+	conn *sql.Conn  // connection of the transaction, released when it ends
+	log  *changeLog // changes made by the transaction
+	n    *int       // savepoints created by the transaction
+	sp   string     // savepoint name, empty for the outermost transaction
+	mark int        // changes made before the savepoint
+	done bool
+}
+
+// Commit the transaction. If tx is a savepoint it is released instead.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	if tx.sp == "" {
+		defer tx.conn.Close()
+		if err := tx.end(OpCommit, "", tx.tx.Commit); err != nil {
+			return err
+		}
+		tx.db.feed.flush()
+		return nil
+	}
+	if err := tx.end(OpCommit, "release "+tx.sp, nil); err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+	return nil
+}
+
+// Savepoint starts a nested transaction using a SAVEPOINT. Committing the
+// returned [Tx] releases the savepoint and rolling it back only undoes the
+// changes made since the savepoint was created.
+func (tx *Tx) Savepoint(ctx context.Context) (*Tx, error) {
+	*tx.n++
+	sp := fmt.Sprintf("sp%d", *tx.n)
+	err := tx.hooks.do(ctx, &QueryEvent{Pool: tx.pool, Op: OpBegin, Query: "savepoint " + sp}, func(ctx context.Context) error {
+		_, err := tx.tx.ExecContext(ctx, "savepoint "+sp)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create savepoint: %w", err)
+	}
+	spx := &Tx{txn: tx.txn, log: tx.log, n: tx.n, sp: sp, mark: tx.log.savepoint()}
+	spx.ctx = ctx
+	return spx, nil
 }
 
-// Commit the transaction.
-func (tx *Tx) Commit() error { return tx.tx.Commit() }
+// DoTx runs f inside a savepoint which is released if f returns nil.
+// Otherwise only the changes made by f are rolled back.
+func (tx *Tx) DoTx(ctx context.Context, f func(context.Context, *Tx) error) error {
+	sp, err := tx.Savepoint(ctx)
+	if err != nil {
+		return err
+	}
+	return sp.do(ctx, f)
+}
+
+func (tx *Tx) do(ctx context.Context, f func(context.Context, *Tx) error) error {
+	defer tx.Rollback()
+	if err := f(NewContext(ctx, tx), tx); err != nil {
+		return fmt.Errorf("run transaction: %w", err)
+	}
+	if tx.done {
+		// f has already committed or rolled back
+		return nil
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit read/write transactions: %w", err)
+	}
+	return nil
+}
 
 // Exec executes a query without returning any rows. The args are for any placeholder parameters in the query.
 func (tx *Tx) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return tx.tx.ExecContext(ctx, query, args...)
+	return tx.exec(ctx, query, args...)
 }
 
 // Query executes a query that returns rows, typically a SELECT. The args are for any placeholder parameters in the query.
 func (tx *Tx) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	return tx.tx.QueryContext(ctx, query, args...)
+	return tx.query(ctx, query, args...)
 }
 
 // QueryRow executes a query that is expected to return at most one row.
 func (tx *Tx) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
-	return tx.tx.QueryRowContext(ctx, query, args...)
+	return tx.queryRow(ctx, query, args...)
 }
 
-// Rollback the transaction.
-func (tx *Tx) Rollback() error { return tx.tx.Rollback() }
+// Rollback the transaction. If tx is a savepoint only the changes made since
+// it was created are undone.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	if tx.sp == "" {
+		defer tx.conn.Close()
+		return tx.end(OpRollback, "", tx.tx.Rollback)
+	}
+	// rolling back to a savepoint leaves it on the stack so release it too
+	if err := tx.end(OpRollback, "rollback to "+tx.sp+"; release "+tx.sp, nil); err != nil {
+		return fmt.Errorf("rollback to savepoint: %w", err)
+	}
+	tx.log.rollbackTo(tx.mark)
+	return nil
+}
 
-// Up from the current version.
-func Up(ctx context.Context, filename string, fsys fs.FS) (*DB, error) {
-	db, err := Open(filename)
-	if err != nil {
-		return nil, err
+// ReadTx is a read-only transaction. It implements [Querier] but not
+// [Executor].
+type ReadTx struct {
+	txn
+	conn *sql.Conn // query only until the transaction ends
+}
+
+// Query executes a query that returns rows, typically a SELECT. The args are for any placeholder parameters in the query.
+func (tx *ReadTx) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return tx.query(ctx, query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row.
+func (tx *ReadTx) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.queryRow(ctx, query, args...)
+}
+
+// Rollback ends the transaction, releasing its snapshot.
+func (tx *ReadTx) Rollback() error {
+	err := tx.end(OpRollback, "", tx.tx.Rollback)
+	if tx.conn != nil {
+		// the transaction may have been rolled back by its context
+		err = errors.Join(err, releaseQueryOnly(tx.conn))
+		tx.conn = nil
 	}
-	if err := migrate.Up(ctx, db.wc, fsys); err != nil {
-		return nil, fmt.Errorf("running up migrations: %w", err)
+	return err
+}
+
+// txn is the state shared by [Tx] and [ReadTx].
+type txn struct {
+	db    *DB
+	tx    *sql.Tx
+	ctx   context.Context // passed to the hooks when the transaction ends
+	pool  string
+	stmts *stmtCache
+	hooks hooks
+}
+
+func (db *DB) txn(ctx context.Context, tx *sql.Tx, pool string) txn {
+	stmts := db.wcache
+	if pool == PoolRead {
+		stmts = db.rcache
 	}
-	return db, nil
+	return txn{db: db, tx: tx, ctx: ctx, pool: pool, stmts: stmts, hooks: db.hooks}
+}
+
+func (t *txn) exec(ctx context.Context, query string, args ...any) (res sql.Result, err error) {
+	e := &QueryEvent{Pool: t.pool, Op: OpExec, Query: query, Args: args}
+	err = t.hooks.do(ctx, e, func(ctx context.Context) error {
+		if stmt, ok := t.stmts.txStmt(ctx, t.tx, query); ok {
+			res, err = stmt.ExecContext(ctx, args...)
+			return err
+		}
+		res, err = t.tx.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+func (t *txn) query(ctx context.Context, query string, args ...any) (rows *sql.Rows, err error) {
+	e := &QueryEvent{Pool: t.pool, Op: OpQuery, Query: query, Args: args}
+	err = t.hooks.do(ctx, e, func(ctx context.Context) error {
+		if stmt, ok := t.stmts.txStmt(ctx, t.tx, query); ok {
+			rows, err = stmt.QueryContext(ctx, args...)
+			return err
+		}
+		rows, err = t.tx.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (t *txn) queryRow(ctx context.Context, query string, args ...any) (row *sql.Row) {
+	e := &QueryEvent{Pool: t.pool, Op: OpQuery, Query: query, Args: args}
+	t.hooks.do(ctx, e, func(ctx context.Context) error {
+		if stmt, ok := t.stmts.txStmt(ctx, t.tx, query); ok {
+			row = stmt.QueryRowContext(ctx, args...)
+			return row.Err()
+		}
+		row = t.tx.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
+
+// end reports the end of a transaction to the hooks. If f is nil the query is
+// executed instead.
+func (t *txn) end(op, query string, f func() error) error {
+	if f == nil {
+		f = func() error {
+			_, err := t.tx.Exec(query)
+			return err
+		}
+	}
+	return t.hooks.do(t.ctx, &QueryEvent{Pool: t.pool, Op: op, Query: query}, func(context.Context) error { return f() })
+}
+
+// Up from the current version.
+func Up(ctx context.Context, filename string, fsys fs.FS, opts ...Option) (*DB, error) {
+	return (&FS{fsys: fsys}).Up(ctx, filename, opts...)
 }
 
 func max(x, y int) int {
@@ -145,12 +490,34 @@ func max(x, y int) int {
 
 // FS
 type FS struct {
-	fsys fs.FS
+	fsys    fs.FS
+	onDrift func(error)
 }
 
 // Up from the current version.
-func (fs *FS) Up(ctx context.Context, filename string) (*DB, error) {
-	return Up(ctx, filename, fs.fsys)
+//
+// Up fails if a migration that has already been applied has since changed,
+// unless [FS.OnDrift] has been set.
+func (fs *FS) Up(ctx context.Context, filename string, opts ...Option) (*DB, error) {
+	db, err := Open(filename, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.up(ctx, db); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	return db, nil
+}
+
+func (fs *FS) up(ctx context.Context, db *DB) error {
+	if err := fs.verify(ctx, db); err != nil {
+		return err
+	}
+	if err := fs.migrator(db).MigrateUp(ctx); err != nil {
+		return fmt.Errorf("running up migrations: %w", err)
+	}
+	db.feed.flush()
+	return nil
 }
 
 // NewFS returns a [FS]
@@ -162,26 +529,45 @@ func NewFS(fsys fs.FS, dir string) (*FS, error) {
 	return &FS{fsys: fsys}, err
 }
 
-func pragma() string {
-	v := map[string]string{
-		"_journal_mode": "wal",
-		"_busy_timeout": "5000",
-		"_synchronous":  "normal",
-		"_cache_size":   "1000000000",
-		"_foreign_keys": "true",
-		"_txlock":       "immediate",
-		"_temp_store":   "memory",
-		"_mmap_size":    "3000000000",
+// connector returns a [driver.Connector] for a pool using the txlock mode
+// when beginning transactions. If feed is not nil it records the changes made
+// on each new connection.
+func (c *config) connector(filename, txlock string, feed *changeFeed) driver.Connector {
+	v := c.values()
+	v.Set("_txlock", txlock)
+	return &connector{
+		driver: &sqlite3.SQLiteDriver{ConnectHook: c.connect},
+		dsn:    fmt.Sprintf("file:%s?%s", filename, v.Encode()),
+		feed:   feed,
 	}
+}
 
-	var buf strings.Builder
-	for k, val := range v {
-		if buf.Len() > 0 {
-			buf.WriteByte('&')
+// connect registers the functions, applies the pragmas and attaches the
+// databases to a new connection.
+func (c *config) connect(conn *sqlite3.SQLiteConn) error {
+	for _, f := range c.register {
+		if err := f(conn); err != nil {
+			return err
+		}
+	}
+	keys := make([]string, 0, len(c.pragma))
+	for k := range c.pragma {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if _, err := conn.Exec(fmt.Sprintf("pragma %s = %s", k, c.pragma[k]), nil); err != nil {
+			return fmt.Errorf("set pragma %s: %w", k, err)
 		}
-		buf.WriteString(k)
-		buf.WriteByte('=')
-		buf.WriteString(val)
 	}
-	return buf.String()
+	return c.attachAll(conn)
+}
+
+// releaseQueryOnly allows writes on the connection of a [ReadTx] again and
+// returns it to the pool. If that fails the connection is discarded.
+func releaseQueryOnly(conn *sql.Conn) error {
+	if _, err := conn.ExecContext(context.Background(), "pragma query_only = 0"); err != nil {
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	return conn.Close()
 }
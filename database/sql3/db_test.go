@@ -3,16 +3,19 @@ package sql3_test
 import (
 	"context"
 	"embed"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
 	"go.adoublef.dev/is"
 	. "go.adoublef.dev/sdk/database/sql3"
 )
 
-//go:embed all:testdata/*.up.sql
+//go:embed all:testdata/*.sql
 var embedFS embed.FS
 var sqlFS, _ = NewFS(embedFS, "testdata")
 
@@ -60,9 +63,162 @@ func Test_DB_Tx(t *testing.T) {
 	}))
 }
 
-func testRoundTrip(f func(*DB)) func(*testing.T) {
+func Test_Tx_DoTx(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		err := db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			_, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "a", 1)
+			if err != nil {
+				return err
+			}
+			return tx.DoTx(ctx, func(ctx context.Context, tx *Tx) error {
+				_, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "b", 2)
+				return err
+			})
+		})
+		is.NoErr(err) // (sql3.DB).DoTx
+
+		var n int
+		err = db.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 2)
+	}))
+
+	t.Run("Rollback", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		errInner := errors.New("inner")
+		err := db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			_, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "a", 1)
+			if err != nil {
+				return err
+			}
+			err = tx.DoTx(ctx, func(ctx context.Context, tx *Tx) error {
+				_, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "b", 2)
+				if err != nil {
+					return err
+				}
+				return errInner
+			})
+			is.Err(err, errInner) // (sql3.Tx).DoTx
+			return nil
+		})
+		is.NoErr(err) // (sql3.DB).DoTx
+
+		var id string
+		err = db.QueryRow(context.TODO(), `select group_concat(id) from tests`).Scan(&id)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(id, "a")
+	}))
+
+	t.Run("Commit", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		err := db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			_, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "a", 1)
+			if err != nil {
+				return err
+			}
+			return tx.Commit()
+		})
+		is.NoErr(err) // (sql3.DB).DoTx
+	}))
+}
+
+func Test_DB_ReadTx(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "a", 1)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		err = db.DoReadTx(context.TODO(), func(ctx context.Context, tx *ReadTx) error {
+			var n int
+			if err := tx.QueryRow(ctx, `select count(*) from tests`).Scan(&n); err != nil {
+				return err
+			}
+			is.Equal(n, 1)
+
+			// writer is not blocked by the reader
+			_, err := db.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "b", 2)
+			is.NoErr(err) // (sql3.DB).Exec
+
+			if err := tx.QueryRow(ctx, `select count(*) from tests`).Scan(&n); err != nil {
+				return err
+			}
+			is.Equal(n, 1) // snapshot
+			return nil
+		})
+		is.NoErr(err) // (sql3.DB).DoReadTx
+	}))
+
+	t.Run("QueryOnly", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		err := db.DoReadTx(context.TODO(), func(ctx context.Context, tx *ReadTx) error {
+			var id string
+			return tx.QueryRow(ctx, `insert into tests (id, counter) values (?, ?) returning id`, "a", 1).Scan(&id)
+		})
+		is.True(err != nil) // (sql3.DB).DoReadTx
+
+		var n int
+		err = db.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 0)
+
+		// connections are only query only for the read transaction
+		var id string
+		err = db.QueryRow(context.TODO(), `insert into tests (id, counter) values (?, ?) returning id`, "a", 1).Scan(&id)
+		is.NoErr(err) // (sql3.DB).QueryRow
+	}))
+}
+
+func Test_DB_DoTx(t *testing.T) {
+	var retries int
+	t.Run("Retry", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		var attempts int
+		err := db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			if attempts++; attempts < 3 {
+				return sqlite3.Error{Code: sqlite3.ErrBusy}
+			}
+			_, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "a", attempts)
+			return err
+		})
+		is.NoErr(err) // (sql3.DB).DoTx
+		is.Equal(retries, 2)
+	}, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		OnRetry:     func(int, error) { retries++ },
+	})))
+
+	t.Run("Exhausted", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		err := db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			return sqlite3.Error{Code: sqlite3.ErrLocked}
+		})
+		is.True(IsBusy(err)) // (sql3.DB).DoTx
+	}, WithRetryPolicy(RetryPolicy{MaxAttempts: 2})))
+
+	t.Run("NoMinBackoff", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		t1 := time.Now()
+		err := db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		})
+		is.True(IsBusy(err))                           // (sql3.DB).DoTx
+		is.True(time.Since(t1) < 500*time.Millisecond) // retries without delay
+	}, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, MaxBackoff: time.Second})))
+}
+
+func testRoundTrip(f func(*DB), opts ...Option) func(*testing.T) {
 	return func(t *testing.T) {
-		db, err := sqlFS.Up(context.TODO(), t.TempDir()+"/test.db")
+		db, err := sqlFS.Up(context.TODO(), t.TempDir()+"/test.db", opts...)
 		if err != nil {
 			t.Fatalf("sql3.Up: %v", err)
 		}
@@ -0,0 +1,158 @@
+package sql3
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type connector struct {
+	driver *sqlite3.SQLiteDriver
+	dsn    string
+	feed   *changeFeed // records the changes made on a write connection
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	dc, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{SQLiteConn: dc.(*sqlite3.SQLiteConn)}
+	if c.feed != nil {
+		cn.log = c.feed.register(cn.SQLiteConn)
+	}
+	return cn, nil
+}
+
+func (c *connector) Driver() driver.Driver { return c.driver }
+
+// conn wraps a driver connection so that the rows of a query can report it to
+// the hooks once they are closed and the changes of a transaction are only
+// delivered once it has committed.
+type conn struct {
+	*sqlite3.SQLiteConn
+	log *changeLog // nil unless on the write pool
+}
+
+// driverConn returns the driver connection given to [sql.Conn.Raw].
+func driverConn(dc any) (*conn, error) {
+	c, ok := dc.(*conn)
+	if !ok {
+		return nil, fmt.Errorf("unexpected driver connection %T", dc)
+	}
+	return c, nil
+}
+
+// settle delivers the changes of a transaction committed by a statement
+// that succeeded, otherwise the transaction did not commit and they are
+// discarded.
+func (c *conn) settle(err error) {
+	if c.log != nil {
+		c.log.settle(err == nil)
+	}
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r, err := c.SQLiteConn.ExecContext(ctx, query, args)
+	c.settle(err)
+	return r, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r, err := c.SQLiteConn.QueryContext(ctx, query, args)
+	if err != nil {
+		c.settle(err)
+		return nil, err
+	}
+	return c.newRows(ctx, r), nil
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	s, err := c.SQLiteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{SQLiteStmt: s.(*sqlite3.SQLiteStmt), c: c}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	t, err := c.SQLiteConn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{Tx: t, c: c}, nil
+}
+
+type tx struct {
+	driver.Tx
+	c *conn
+}
+
+func (t *tx) Commit() error {
+	err := t.Tx.Commit()
+	t.c.settle(err)
+	return err
+}
+
+type stmt struct {
+	*sqlite3.SQLiteStmt
+	c *conn
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	r, err := s.SQLiteStmt.ExecContext(ctx, args)
+	s.c.settle(err)
+	return r, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	r, err := s.SQLiteStmt.QueryContext(ctx, args)
+	if err != nil {
+		s.c.settle(err)
+		return nil, err
+	}
+	return s.c.newRows(ctx, r), nil
+}
+
+// rows finishes the pending event of its query and settles the changes it
+// committed when it is closed.
+type rows struct {
+	*sqlite3.SQLiteRows
+	c   *conn
+	p   *pendingEvent // nil if the query is not reported to the hooks
+	err error         // first error reading a row
+}
+
+// newRows returns r, finishing the pending event of ctx when it is closed.
+func (c *conn) newRows(ctx context.Context, r driver.Rows) driver.Rows {
+	sr, ok := r.(*sqlite3.SQLiteRows)
+	if !ok {
+		return r
+	}
+	p, ok := claim(ctx)
+	if !ok && c.log == nil {
+		return r
+	}
+	return &rows{SQLiteRows: sr, c: c, p: p}
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	err := r.SQLiteRows.Next(dest)
+	if err != nil && !errors.Is(err, io.EOF) && r.err == nil {
+		r.err = err
+	}
+	return err
+}
+
+func (r *rows) Close() error {
+	err := r.SQLiteRows.Close()
+	r.c.settle(errors.Join(r.err, err))
+	if r.p != nil {
+		r.p.finish(errors.Join(r.err, err))
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package sql3
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Dump writes the database to w as SQL text in the style of the sqlite3
+// shell's .dump command: the tables, then their rows as INSERT statements
+// ordered by primary key, then the indexes, views and triggers. The output
+// only depends on the content of the database so dumps can be diffed.
+//
+// The content of virtual tables is not dumped, indexes such as FTS5 must be
+// rebuilt once loaded.
+func (db *DB) Dump(ctx context.Context, w io.Writer) error {
+	return db.DoReadTx(ctx, func(ctx context.Context, tx *ReadTx) error {
+		bw := bufio.NewWriter(w)
+		if err := dump(ctx, tx, bw); err != nil {
+			return err
+		}
+		return bw.Flush()
+	})
+}
+
+type schemaObject struct {
+	Type string `db:"type"`
+	Name string `db:"name"`
+	SQL  string `db:"sql"`
+}
+
+func dump(ctx context.Context, tx *ReadTx, w io.Writer) error {
+	objects, err := QueryAll[schemaObject](ctx, tx, `select type, name, sql from sqlite_schema
+		where sql is not null and name not like 'sqlite_%'
+		order by case type when 'table' then 0 when 'index' then 1 when 'view' then 2 else 3 end,
+			case type when 'table' then name end, rowid`)
+	if err != nil {
+		return fmt.Errorf("dump schema: %w", err)
+	}
+	var virtual []string
+	for _, o := range objects {
+		if o.Type == "table" && strings.HasPrefix(strings.ToLower(o.SQL), "create virtual table") {
+			virtual = append(virtual, o.Name)
+		}
+	}
+	// shadow tables are created along with their virtual table
+	shadows, err := QueryAll[string](ctx, tx, `select name from pragma_table_list where schema = 'main' and type = 'shadow'`)
+	if err != nil {
+		return fmt.Errorf("dump schema: %w", err)
+	}
+	shadow := func(name string) bool { return slices.Contains(shadows, name) }
+
+	fmt.Fprint(w, "begin;\npragma defer_foreign_keys = on;\n")
+	for _, o := range objects {
+		if o.Type != "table" || shadow(o.Name) {
+			continue
+		}
+		fmt.Fprintf(w, "%s;\n", o.SQL)
+		if slices.Contains(virtual, o.Name) {
+			continue
+		}
+		if err := dumpRows(ctx, tx, w, o.Name); err != nil {
+			return err
+		}
+	}
+	var seq bool
+	err = tx.QueryRow(ctx, `select count(*) > 0 from sqlite_schema where name = 'sqlite_sequence'`).Scan(&seq)
+	if err != nil {
+		return fmt.Errorf("dump schema: %w", err)
+	}
+	if seq {
+		fmt.Fprint(w, "delete from sqlite_sequence;\n")
+		if err := dumpRows(ctx, tx, w, "sqlite_sequence"); err != nil {
+			return err
+		}
+	}
+	for _, o := range objects {
+		if o.Type != "table" {
+			fmt.Fprintf(w, "%s;\n", o.SQL)
+		}
+	}
+	_, err = fmt.Fprint(w, "commit;\n")
+	return err
+}
+
+type tableColumn struct {
+	Name   string `db:"name"`
+	PK     int    `db:"pk"`
+	Hidden int    `db:"hidden"`
+}
+
+// dumpRows writes the rows of table as INSERT statements.
+func dumpRows(ctx context.Context, tx *ReadTx, w io.Writer, table string) error {
+	columns, err := QueryAll[tableColumn](ctx, tx, `select name, pk, hidden from pragma_table_xinfo(?) order by cid`, table)
+	if err != nil {
+		return fmt.Errorf("dump table %s: %w", table, err)
+	}
+	var names, values, order []string
+	for _, c := range columns {
+		// generated columns cannot be inserted
+		if c.Hidden != 0 {
+			continue
+		}
+		names = append(names, quoteIdent(c.Name))
+		values = append(values, fmt.Sprintf("quote(%s)", quoteIdent(c.Name)))
+	}
+	slices.SortFunc(columns, func(a, b tableColumn) int { return a.PK - b.PK })
+	for _, c := range columns {
+		if c.PK > 0 {
+			order = append(order, quoteIdent(c.Name))
+		}
+	}
+	if len(order) == 0 {
+		order = []string{"rowid"}
+	}
+	if table == "sqlite_sequence" {
+		order = []string{"name"}
+	}
+	query := fmt.Sprintf("select %s from %s order by %s", strings.Join(values, " || ', ' || "), quoteIdent(table), strings.Join(order, ", "))
+	prefix := fmt.Sprintf("insert into %s (%s) values (", quoteIdent(table), strings.Join(names, ", "))
+	for row, err := range QuerySeq[string](ctx, tx, query) {
+		if err != nil {
+			return fmt.Errorf("dump table %s: %w", table, err)
+		}
+		if _, err := fmt.Fprintf(w, "%s%s);\n", prefix, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quoteIdent quotes an SQL identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Load runs the SQL text from r, such as the output of [DB.Dump], on the write
+// connection. If a statement fails an open transaction is rolled back.
+func (db *DB) Load(ctx context.Context, r io.Reader) error {
+	script, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read script: %w", err)
+	}
+	conn, err := db.wc.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire write connection: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, string(script)); err != nil {
+		// fails with no transaction active unless the script began one
+		conn.ExecContext(context.WithoutCancel(ctx), "rollback")
+		return fmt.Errorf("load script: %w", err)
+	}
+	db.feed.flush()
+	return nil
+}
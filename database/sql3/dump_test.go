@@ -0,0 +1,103 @@
+package sql3_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_DB_Dump(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := db.Exec(context.TODO(), `create table values_ (id integer primary key autoincrement, i int, r real, t text, b blob)`)
+		is.NoErr(err) // (sql3.DB).Exec
+		_, err = db.Exec(context.TODO(), `insert into values_ (i, r, t, b) values (?, ?, ?, ?), (null, null, null, null)`,
+			42, 0.1, "it's\nmultiline", []byte{0xde, 0xad})
+		is.NoErr(err) // (sql3.DB).Exec
+		_, err = db.Exec(context.TODO(), `insert into tests (id, counter) values ('b', 2), ('a', 1)`)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		var dump bytes.Buffer
+		err = db.Dump(context.TODO(), &dump)
+		is.NoErr(err) // (sql3.DB).Dump
+		// rows are ordered by primary key
+		is.True(strings.Index(dump.String(), "values ('a', 1)") < strings.Index(dump.String(), "values ('b', 2)"))
+
+		loaded, err := Open("", WithMemory())
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { loaded.Close() })
+
+		err = loaded.Load(context.TODO(), bytes.NewReader(dump.Bytes()))
+		is.NoErr(err) // (sql3.DB).Load
+
+		var again bytes.Buffer
+		err = loaded.Dump(context.TODO(), &again)
+		is.NoErr(err) // (sql3.DB).Dump
+		is.Equal(again.String(), dump.String())
+
+		v, err := sqlFS.Version(context.TODO(), loaded)
+		is.NoErr(err) // (sql3.FS).Version
+		is.Equal(v, "1")
+
+		var (
+			r float64
+			s string
+			b []byte
+		)
+		err = loaded.QueryRow(context.TODO(), `select r, t, b from values_ where id = 1`).Scan(&r, &s, &b)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(r, 0.1)
+		is.Equal(s, "it's\nmultiline")
+		is.Equal(b, []byte{0xde, 0xad})
+	}))
+
+	t.Run("Virtual", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := db.Exec(context.TODO(), `create virtual table places using rtree (id, x0, x1)`)
+		is.NoErr(err) // (sql3.DB).Exec
+		// a regular table whose name starts with that of the virtual table
+		_, err = db.Exec(context.TODO(), `create table places_history (id integer primary key, name text)`)
+		is.NoErr(err) // (sql3.DB).Exec
+		_, err = db.Exec(context.TODO(), `insert into places_history (name) values ('a')`)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		var dump bytes.Buffer
+		err = db.Dump(context.TODO(), &dump)
+		is.NoErr(err) // (sql3.DB).Dump
+		is.True(strings.Contains(dump.String(), "CREATE TABLE places_history"))
+		is.True(!strings.Contains(dump.String(), "places_node")) // shadow table
+
+		loaded, err := Open("", WithMemory())
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { loaded.Close() })
+
+		err = loaded.Load(context.TODO(), bytes.NewReader(dump.Bytes()))
+		is.NoErr(err) // (sql3.DB).Load
+
+		name, err := QueryOne[string](context.TODO(), loaded, `select name from places_history`)
+		is.NoErr(err) // sql3.QueryOne
+		is.Equal(name, "a")
+	}))
+
+	t.Run("Rollback", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := Open("", WithMemory())
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		err = db.Load(context.TODO(), strings.NewReader("begin;\ncreate table a (id int);\ninsert into missing values (1);\ncommit;\n"))
+		is.True(err != nil) // (sql3.DB).Load
+
+		var n int
+		err = db.QueryRow(context.TODO(), `select count(*) from sqlite_schema`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 0)
+	})
+}
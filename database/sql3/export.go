@@ -0,0 +1,286 @@
+package sql3
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrDecrypt is returned by [FS.Import] when an export cannot be decrypted,
+// either because the passphrase is wrong or the export has been modified.
+var ErrDecrypt = errors.New("sql3: wrong passphrase or corrupt export")
+
+const (
+	exportMagic      = "sql3enc\x01"
+	exportSaltSize   = 16
+	exportIterations = 600_000
+	exportChunkSize  = 64 << 10
+)
+
+// Export writes a backup of the database to w, encrypted with AES-256-GCM
+// using a key derived from passphrase with PBKDF2-SHA256. The export can be
+// restored with [FS.Import].
+//
+// The database is copied into memory from a snapshot on the read pool so the
+// plaintext is never written to disk.
+func (db *DB) Export(ctx context.Context, w io.Writer, passphrase string) error {
+	b, err := db.serialize(ctx)
+	if err != nil {
+		return err
+	}
+	ew, err := newEncrypter(w, passphrase)
+	if err != nil {
+		return err
+	}
+	if _, err := ew.Write(b); err != nil {
+		return err
+	}
+	return ew.Close()
+}
+
+// serialize returns a copy of the database read from a snapshot on the read
+// pool.
+func (db *DB) serialize(ctx context.Context) ([]byte, error) {
+	conn, err := db.rc.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire read connection: %w", err)
+	}
+	defer conn.Close()
+	var b []byte
+	err = conn.Raw(func(dc any) error {
+		cn, err := driverConn(dc)
+		if err != nil {
+			return err
+		}
+		if _, err := cn.Exec("begin; select 1 from sqlite_schema limit 1", nil); err != nil {
+			return fmt.Errorf("begin read transaction: %w", err)
+		}
+		defer cn.Exec("rollback", nil)
+		if b, err = cn.Serialize("main"); err != nil {
+			return fmt.Errorf("serialize database: %w", err)
+		}
+		return nil
+	})
+	return b, err
+}
+
+// Import restores an export made by [DB.Export] to filename, which must not
+// already exist, then runs the migrations from the restored version as with
+// [FS.Up]. If the options include [WithMemory] the export is restored into a
+// new in-memory database instead.
+func (fs *FS) Import(ctx context.Context, filename string, r io.Reader, passphrase string, opts ...Option) (*DB, error) {
+	c := newConfig(opts...)
+	if c.memory {
+		return fs.importMemory(ctx, r, passphrase, opts...)
+	}
+	if _, err := os.Stat(filename); err == nil {
+		return nil, fmt.Errorf("import: %s already exists", filename)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), c.dirMode); err != nil {
+		return nil, fmt.Errorf("create directory for database files: %w", err)
+	}
+	tmp, err := decryptTemp(filepath.Dir(filename), r, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		return nil, errors.Join(fmt.Errorf("import: %w", err), os.Remove(tmp))
+	}
+	return fs.Up(ctx, filename, opts...)
+}
+
+func (fs *FS) importMemory(ctx context.Context, r io.Reader, passphrase string, opts ...Option) (*DB, error) {
+	dir, err := os.MkdirTemp("", "sql3-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	tmp, err := decryptTemp(dir, r, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	src, err := Open(tmp, WithReadOnly())
+	if err != nil {
+		return nil, err
+	}
+	db, err := src.Clone(ctx, opts...)
+	if err = errors.Join(err, src.Close()); err != nil {
+		return nil, err
+	}
+	if err := fs.up(ctx, db); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	return db, nil
+}
+
+// decryptTemp decrypts r to a temporary file in dir and returns its name.
+func decryptTemp(dir string, r io.Reader, passphrase string) (string, error) {
+	dr, err := newDecrypter(r, passphrase)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, "sql3-import-*")
+	if err != nil {
+		return "", fmt.Errorf("create temporary file: %w", err)
+	}
+	_, err = io.Copy(f, dr)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err = errors.Join(err, f.Close()); err != nil {
+		return "", errors.Join(err, os.Remove(f.Name()))
+	}
+	return f.Name(), nil
+}
+
+// The export is a header followed by chunks of ciphertext. The header holds the
+// magic, the salt and the PBKDF2 iterations and is authenticated as the
+// additional data of every chunk. Each chunk is prefixed by its length and
+// sealed with a nonce made from its index and whether it is the last chunk,
+// so chunks cannot be reordered, dropped or truncated without detection.
+
+type encrypter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	buf    []byte
+	n      uint64
+}
+
+func newEncrypter(w io.Writer, passphrase string) (*encrypter, error) {
+	salt := make([]byte, exportSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	header := binary.BigEndian.AppendUint32(append([]byte(exportMagic), salt...), exportIterations)
+	aead, err := newAEAD(passphrase, salt, exportIterations)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("write export header: %w", err)
+	}
+	return &encrypter{w: w, aead: aead, header: header, buf: make([]byte, 0, exportChunkSize)}, nil
+}
+
+func (e *encrypter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		if len(e.buf) == exportChunkSize {
+			if err := e.seal(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(e.buf[len(e.buf):exportChunkSize], p)
+		e.buf, p, n = e.buf[:len(e.buf)+m], p[m:], n+m
+	}
+	return n, nil
+}
+
+// Close writes the last chunk. It does not close the underlying writer.
+func (e *encrypter) Close() error { return e.seal(true) }
+
+func (e *encrypter) seal(last bool) error {
+	ct := e.aead.Seal(nil, chunkNonce(e.n, last), e.buf, e.header)
+	if _, err := e.w.Write(binary.BigEndian.AppendUint32(nil, uint32(len(ct)))); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	if _, err := e.w.Write(ct); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	e.buf, e.n = e.buf[:0], e.n+1
+	return nil
+}
+
+type decrypter struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	header []byte
+	buf    []byte // plaintext not yet read
+	n      uint64
+	done   bool
+}
+
+func newDecrypter(r io.Reader, passphrase string) (*decrypter, error) {
+	header := make([]byte, len(exportMagic)+exportSaltSize+4)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(exportMagic)]) != exportMagic {
+		return nil, fmt.Errorf("import: not an export: %w", ErrDecrypt)
+	}
+	salt := header[len(exportMagic) : len(exportMagic)+exportSaltSize]
+	iterations := binary.BigEndian.Uint32(header[len(exportMagic)+exportSaltSize:])
+	if iterations == 0 || iterations > 10*exportIterations {
+		return nil, fmt.Errorf("import: invalid iterations: %w", ErrDecrypt)
+	}
+	aead, err := newAEAD(passphrase, salt, int(iterations))
+	if err != nil {
+		return nil, err
+	}
+	return &decrypter{r: bufio.NewReader(r), aead: aead, header: header}, nil
+}
+
+func (d *decrypter) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decrypter) open() error {
+	var size [4]byte
+	if _, err := io.ReadFull(d.r, size[:]); err != nil {
+		return fmt.Errorf("import: truncated export: %w", ErrDecrypt)
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > exportChunkSize+uint32(d.aead.Overhead()) {
+		return fmt.Errorf("import: invalid chunk: %w", ErrDecrypt)
+	}
+	ct := make([]byte, n)
+	if _, err := io.ReadFull(d.r, ct); err != nil {
+		return fmt.Errorf("import: truncated export: %w", ErrDecrypt)
+	}
+	// the last chunk is only known by trying to open it as such
+	_, err := d.r.Peek(1)
+	last := err == io.EOF
+	pt, err := d.aead.Open(ct[:0], chunkNonce(d.n, last), ct, d.header)
+	if err != nil {
+		return ErrDecrypt
+	}
+	d.buf, d.n, d.done = pt, d.n+1, last
+	return nil
+}
+
+// chunkNonce returns the nonce for the chunk at index n.
+func chunkNonce(n uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], n)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func newAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(passphrase), salt, iterations, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,91 @@
+package sql3_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_DB_Export(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		for i, id := range []string{"a", "b", "c"} {
+			_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, id, i)
+			is.NoErr(err) // (sql3.DB).Exec
+		}
+
+		var buf bytes.Buffer
+		err := db.Export(context.TODO(), &buf, "secret")
+		is.NoErr(err) // (sql3.DB).Export
+		is.True(!bytes.Contains(buf.Bytes(), []byte("SQLite format 3")))
+
+		imported, err := sqlFS.Import(context.TODO(), testFilename(t, "import.db"), bytes.NewReader(buf.Bytes()), "secret")
+		is.NoErr(err) // (sql3.FS).Import
+		t.Cleanup(func() { imported.Close() })
+
+		ids, err := QueryAll[string](context.TODO(), imported, `select id from tests order by id`)
+		is.NoErr(err) // sql3.QueryAll
+		is.Equal(ids, []string{"a", "b", "c"})
+
+		v, err := sqlFS.Version(context.TODO(), imported)
+		is.NoErr(err) // (sql3.FS).Version
+		is.Equal(v, "1")
+	}))
+
+	t.Run("NoTempFile", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := sqlFS.Up(context.TODO(), testFilename(t, "test.db"))
+		is.NoErr(err) // (sql3.FS).Up
+		t.Cleanup(func() { db.Close() })
+
+		// the plaintext cannot be written to a temporary file
+		t.Setenv("TMPDIR", filepath.Join(t.TempDir(), "missing"))
+		err = db.Export(context.TODO(), io.Discard, "secret")
+		is.NoErr(err) // (sql3.DB).Export
+	})
+
+	t.Run("Memory", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "a", 1)
+		is.NoErr(err) // (sql3.DB).Exec
+		// an export of an older version is migrated once imported
+		err = sqlFS.To(context.TODO(), db, "0")
+		is.NoErr(err) // (sql3.FS).To
+
+		var buf bytes.Buffer
+		err = db.Export(context.TODO(), &buf, "secret")
+		is.NoErr(err) // (sql3.DB).Export
+
+		imported, err := sqlFS.Import(context.TODO(), "", &buf, "secret", WithMemory())
+		is.NoErr(err) // (sql3.FS).Import
+		t.Cleanup(func() { imported.Close() })
+
+		v, err := sqlFS.Version(context.TODO(), imported)
+		is.NoErr(err) // (sql3.FS).Version
+		is.Equal(v, "1")
+	}))
+
+	t.Run("ErrDecrypt", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		var buf bytes.Buffer
+		err := db.Export(context.TODO(), &buf, "secret")
+		is.NoErr(err) // (sql3.DB).Export
+
+		_, err = sqlFS.Import(context.TODO(), testFilename(t, "wrong.db"), bytes.NewReader(buf.Bytes()), "wrong")
+		is.True(errors.Is(err, ErrDecrypt)) // wrong passphrase
+
+		truncated := buf.Bytes()[:buf.Len()-100]
+		_, err = sqlFS.Import(context.TODO(), testFilename(t, "truncated.db"), bytes.NewReader(truncated), "secret")
+		is.True(errors.Is(err, ErrDecrypt)) // truncated
+	}))
+}
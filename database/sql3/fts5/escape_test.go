@@ -0,0 +1,26 @@
+package fts5_test
+
+import (
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3/fts5"
+)
+
+func Test_Escape(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"hello world", `"hello" "world"`},
+		{`say "hi"`, `"say" """hi"""`},
+		{"NOT AND OR", `"NOT" "AND" "OR"`},
+		{"col:value (x)", `"col:value" "(x)"`},
+		{"pre*", `"pre"*`},
+		{"  * ", ``},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			is := is.NewRelaxed(t)
+			is.Equal(Escape(tc.in), tc.want)
+		})
+	}
+}
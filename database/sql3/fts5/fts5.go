@@ -0,0 +1,204 @@
+// Package fts5 provides full-text search on a [sql3.DB] using the SQLite FTS5
+// extension.
+//
+// FTS5 is only compiled into the SQLite driver when built with the
+// "sqlite_fts5" build tag.
+package fts5
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.adoublef.dev/sdk/database/sql3"
+)
+
+var nameMatcher = regexp.MustCompile(`^\w+$`)
+
+// An Index is an FTS5 table indexing the columns of a content table. The index
+// stores no copy of the content, it is kept in sync by triggers on the content
+// table.
+type Index struct {
+	Name     string   // Name of the FTS5 table.
+	Table    string   // Name of the content table.
+	RowID    string   // Integer key of the content table, "rowid" if empty.
+	Columns  []string // Columns of the content table to index.
+	Tokenize string   // Tokenizer, e.g. "porter unicode61", the default if empty.
+}
+
+// Create creates the index and the triggers that keep it in sync with the
+// content table, then indexes the existing rows. It does nothing to an index
+// that already exists other than rebuilding it.
+func (ix *Index) Create(ctx context.Context, e sql3.Executor) error {
+	if err := ix.validate(); err != nil {
+		return err
+	}
+	options := []string{fmt.Sprintf("content='%s'", ix.Table), fmt.Sprintf("content_rowid='%s'", ix.rowID())}
+	if ix.Tokenize != "" {
+		options = append(options, fmt.Sprintf("tokenize='%s'", strings.ReplaceAll(ix.Tokenize, "'", "''")))
+	}
+	var (
+		columns = strings.Join(ix.Columns, ", ")
+		newRow  = "new." + ix.rowID() + ", new." + strings.Join(ix.Columns, ", new.")
+		oldRow  = "old." + ix.rowID() + ", old." + strings.Join(ix.Columns, ", old.")
+		insert  = fmt.Sprintf("insert into %s (rowid, %s) values (%s);", ix.Name, columns, newRow)
+		delete  = fmt.Sprintf("insert into %[1]s (%[1]s, rowid, %[2]s) values ('delete', %[3]s);", ix.Name, columns, oldRow)
+	)
+	for _, query := range []string{
+		fmt.Sprintf("create virtual table if not exists %s using fts5(%s, %s)", ix.Name, columns, strings.Join(options, ", ")),
+		fmt.Sprintf("create trigger if not exists %s_ai after insert on %s begin %s end", ix.Name, ix.Table, insert),
+		fmt.Sprintf("create trigger if not exists %s_ad after delete on %s begin %s end", ix.Name, ix.Table, delete),
+		fmt.Sprintf("create trigger if not exists %s_au after update on %s begin %s %s end", ix.Name, ix.Table, delete, insert),
+	} {
+		if _, err := e.Exec(ctx, query); err != nil {
+			return fmt.Errorf("create fts5 index %s: %w", ix.Name, err)
+		}
+	}
+	return ix.Rebuild(ctx, e)
+}
+
+// Rebuild discards the index and indexes every row of the content table.
+func (ix *Index) Rebuild(ctx context.Context, e sql3.Executor) error {
+	if _, err := e.Exec(ctx, fmt.Sprintf("insert into %[1]s (%[1]s) values ('rebuild')", ix.Name)); err != nil {
+		return fmt.Errorf("rebuild fts5 index %s: %w", ix.Name, err)
+	}
+	return nil
+}
+
+// Drop drops the index and its triggers. The content table is not changed.
+func (ix *Index) Drop(ctx context.Context, e sql3.Executor) error {
+	if err := ix.validate(); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		fmt.Sprintf("drop trigger if exists %s_ai", ix.Name),
+		fmt.Sprintf("drop trigger if exists %s_ad", ix.Name),
+		fmt.Sprintf("drop trigger if exists %s_au", ix.Name),
+		fmt.Sprintf("drop table if exists %s", ix.Name),
+	} {
+		if _, err := e.Exec(ctx, query); err != nil {
+			return fmt.Errorf("drop fts5 index %s: %w", ix.Name, err)
+		}
+	}
+	return nil
+}
+
+// A Result is a row of the content table matching a search.
+type Result struct {
+	RowID   int64
+	Rank    float64 // bm25 score, lower is a better match.
+	Snippet string
+}
+
+// A SearchOption configures [Index.Search].
+type SearchOption func(*searchConfig)
+
+type searchConfig struct {
+	limit, offset int
+	column        int
+	before, after string
+	ellipsis      string
+	tokens        int
+	weights       []float64
+}
+
+// WithLimit returns at most n results, skipping the first offset.
+func WithLimit(n, offset int) SearchOption {
+	return func(c *searchConfig) { c.limit, c.offset = n, offset }
+}
+
+// WithSnippet sets how the snippet of each result is made. Matched terms are
+// wrapped in before and after, omitted text is replaced by ellipsis and the
+// snippet is at most tokens long, up to 64. The snippet is taken from the
+// column at index column of [Index.Columns], or the best match if negative.
+func WithSnippet(column int, before, after, ellipsis string, tokens int) SearchOption {
+	return func(c *searchConfig) {
+		c.column, c.before, c.after, c.ellipsis, c.tokens = column, before, after, ellipsis, tokens
+	}
+}
+
+// WithWeights sets the weight of each column of [Index.Columns] when ranking.
+// Columns without a weight have a weight of 1.
+func WithWeights(weights ...float64) SearchOption {
+	return func(c *searchConfig) { c.weights = weights }
+}
+
+// Search returns the rows matching the FTS5 query match, best first. Queries
+// from users should be escaped with [Escape].
+func (ix *Index) Search(ctx context.Context, q sql3.Querier, match string, opts ...SearchOption) ([]Result, error) {
+	if err := ix.validate(); err != nil {
+		return nil, err
+	}
+	c := &searchConfig{limit: -1, column: -1, before: "<b>", after: "</b>", ellipsis: "…", tokens: 16}
+	for _, opt := range opts {
+		opt(c)
+	}
+	rank := ix.Name
+	for _, w := range c.weights {
+		rank += fmt.Sprintf(", %g", w)
+	}
+	query := fmt.Sprintf(`select rowid, bm25(%s) as rank, snippet(%s, ?, ?, ?, ?, ?) from %s where %s match ? order by rank limit ? offset ?`,
+		rank, ix.Name, ix.Name, ix.Name)
+	rows, err := q.Query(ctx, query, c.column, c.before, c.after, c.ellipsis, c.tokens, match, c.limit, c.offset)
+	if err != nil {
+		return nil, fmt.Errorf("search fts5 index %s: %w", ix.Name, err)
+	}
+	defer rows.Close()
+	var rr []Result
+	for rows.Next() {
+		var (
+			r       Result
+			snippet sql.NullString
+		)
+		if err := rows.Scan(&r.RowID, &r.Rank, &snippet); err != nil {
+			return nil, err
+		}
+		r.Snippet = snippet.String
+		rr = append(rr, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search fts5 index %s: %w", ix.Name, err)
+	}
+	return rr, nil
+}
+
+func (ix *Index) rowID() string {
+	if ix.RowID == "" {
+		return "rowid"
+	}
+	return ix.RowID
+}
+
+// validate reports an error if a name would need to be quoted.
+func (ix *Index) validate() error {
+	if len(ix.Columns) == 0 {
+		return fmt.Errorf("fts5: index %s has no columns", ix.Name)
+	}
+	for _, name := range append([]string{ix.Name, ix.Table, ix.rowID()}, ix.Columns...) {
+		if !nameMatcher.MatchString(name) {
+			return fmt.Errorf("fts5: invalid name %q", name)
+		}
+	}
+	return nil
+}
+
+// Escape turns text from a user into an FTS5 query matching rows containing
+// every word of s. Each word is quoted so that FTS5 operators and syntax are
+// matched literally, except a trailing "*" which makes a prefix query.
+func Escape(s string) string {
+	var terms []string
+	for _, f := range strings.Fields(s) {
+		prefix := strings.HasSuffix(f, "*")
+		if f = strings.TrimRight(f, "*"); f == "" {
+			continue
+		}
+		term := `"` + strings.ReplaceAll(f, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
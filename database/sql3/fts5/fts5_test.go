@@ -0,0 +1,82 @@
+//go:build sqlite_fts5 || fts5
+
+package fts5_test
+
+import (
+	"context"
+	"embed"
+	"strings"
+	"testing"
+
+	"go.adoublef.dev/is"
+	"go.adoublef.dev/sdk/database/sql3"
+	. "go.adoublef.dev/sdk/database/sql3/fts5"
+	"go.adoublef.dev/sdk/database/sql3/sql3test"
+)
+
+//go:embed testdata/*.sql
+var embedFS embed.FS
+var sqlFS, _ = sql3.NewFS(embedFS, "testdata")
+
+var testIndex = &Index{Name: "products_fts", Table: "products", RowID: "id", Columns: []string{"name", "description"}, Tokenize: "porter"}
+
+func Test_Index(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+		err := testIndex.Create(context.TODO(), db)
+		is.NoErr(err) // (fts5.Index).Create
+
+		for _, p := range [][2]string{
+			{"Red Kettle", "A kettle for boiling water"},
+			{"Blue Mug", "A mug for drinking tea"},
+			{"Teapot", "Brews tea, pairs with a kettle"},
+		} {
+			_, err := db.Exec(context.TODO(), `insert into products (name, description) values (?, ?)`, p[0], p[1])
+			is.NoErr(err) // (sql3.DB).Exec
+		}
+
+		rr, err := testIndex.Search(context.TODO(), db, Escape("kettle"))
+		is.NoErr(err) // (fts5.Index).Search
+		is.Equal(len(rr), 2)
+		is.Equal(rr[0].RowID, int64(1)) // matches in both columns
+		is.True(strings.Contains(rr[0].Snippet, "<b>Kettle</b>"))
+
+		_, err = db.Exec(context.TODO(), `update products set name = 'Green Kettle' where id = 2`)
+		is.NoErr(err) // (sql3.DB).Exec
+		_, err = db.Exec(context.TODO(), `delete from products where id = 1`)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		rr, err = testIndex.Search(context.TODO(), db, Escape("kettl*"), WithLimit(1, 0))
+		is.NoErr(err) // (fts5.Index).Search
+		is.Equal(len(rr), 1)
+		is.Equal(rr[0].RowID, int64(2))
+	})
+
+	t.Run("Rebuild", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+		_, err := db.Exec(context.TODO(), `insert into products (name, description) values ('Teapot', 'Brews tea')`)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		err = testIndex.Create(context.TODO(), db)
+		is.NoErr(err) // (fts5.Index).Create
+
+		rr, err := testIndex.Search(context.TODO(), db, Escape("brewing"))
+		is.NoErr(err) // (fts5.Index).Search
+		is.Equal(len(rr), 1)
+	})
+
+	t.Run("Escape", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+		err := testIndex.Create(context.TODO(), db)
+		is.NoErr(err) // (fts5.Index).Create
+
+		_, err = testIndex.Search(context.TODO(), db, Escape(`name:"kettle" OR (`))
+		is.NoErr(err) // (fts5.Index).Search
+	})
+}
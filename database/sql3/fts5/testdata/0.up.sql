@@ -0,0 +1 @@
+create table products (id integer primary key, name text, description text);
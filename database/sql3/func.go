@@ -0,0 +1,48 @@
+package sql3
+
+import (
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// WithFunc registers impl as the SQL function name on every connection of
+// both pools. pure reports whether impl always returns the same result for
+// the same arguments. See [sqlite3.SQLiteConn.RegisterFunc] for the supported
+// signatures of impl.
+func WithFunc(name string, impl any, pure bool) Option {
+	return withRegister(func(conn *sqlite3.SQLiteConn) error {
+		if err := conn.RegisterFunc(name, impl, pure); err != nil {
+			return fmt.Errorf("register function %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// WithAggregate registers impl as the SQL aggregate function name on every
+// connection of both pools. impl is a constructor returning a type with Step
+// and Done methods. See [sqlite3.SQLiteConn.RegisterAggregator] for details.
+func WithAggregate(name string, impl any, pure bool) Option {
+	return withRegister(func(conn *sqlite3.SQLiteConn) error {
+		if err := conn.RegisterAggregator(name, impl, pure); err != nil {
+			return fmt.Errorf("register aggregate %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// WithCollation registers cmp as the collating sequence name on every
+// connection of both pools. cmp returns a negative number if a sorts before b,
+// a positive number if it sorts after and zero if they are equal.
+func WithCollation(name string, cmp func(a, b string) int) Option {
+	return withRegister(func(conn *sqlite3.SQLiteConn) error {
+		if err := conn.RegisterCollation(name, cmp); err != nil {
+			return fmt.Errorf("register collation %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+func withRegister(f func(*sqlite3.SQLiteConn) error) Option {
+	return func(c *config) { c.register = append(c.register, f) }
+}
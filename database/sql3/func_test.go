@@ -0,0 +1,49 @@
+package sql3_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+type sumSquares struct{ n int64 }
+
+func (s *sumSquares) Step(v int64) { s.n += v * v }
+
+func (s *sumSquares) Done() int64 { return s.n }
+
+func Test_WithFunc(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := sqlFS.Up(context.TODO(), testFilename(t, "test.db"),
+			WithFunc("regexp", func(re, s string) (bool, error) { return regexp.MatchString(re, s) }, true),
+			WithAggregate("sum_squares", func() *sumSquares { return &sumSquares{} }, true),
+			WithCollation("fold", func(a, b string) int { return strings.Compare(strings.ToLower(a), strings.ToLower(b)) }),
+		)
+		is.NoErr(err) // (sql3.FS).Up
+		t.Cleanup(func() { db.Close() })
+
+		for i, id := range []string{"Ab", "ab", "bc"} {
+			_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, id, i+1)
+			is.NoErr(err) // (sql3.DB).Exec
+		}
+
+		var n int64
+		err = db.QueryRow(context.TODO(), `select count(*) from tests where id regexp '^[a-z]+$'`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, int64(2))
+
+		err = db.QueryRow(context.TODO(), `select sum_squares(counter) from tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, int64(14))
+
+		err = db.QueryRow(context.TODO(), `select count(*) from tests where id = 'ab' collate fold`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, int64(2))
+	})
+}
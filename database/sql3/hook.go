@@ -0,0 +1,135 @@
+package sql3
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pools reported by a [QueryEvent].
+const (
+	PoolRead  = "read"
+	PoolWrite = "write"
+)
+
+// Operations reported by a [QueryEvent].
+const (
+	OpExec     = "exec"
+	OpQuery    = "query"
+	OpBegin    = "begin"
+	OpCommit   = "commit"
+	OpRollback = "rollback"
+)
+
+// A QueryEvent describes a statement run against a [DB].
+type QueryEvent struct {
+	Pool     string // PoolRead or PoolWrite
+	Op       string // One of the Op constants.
+	Query    string // SQL text, empty when beginning or ending a transaction other than a savepoint.
+	Args     []any
+	Duration time.Duration // Set before [Hook.After] is called. For a query it includes reading the rows, which are reported when closed.
+	Err      error         // Set before [Hook.After] is called.
+}
+
+// A Hook observes the statements run against a [DB].
+type Hook interface {
+	// Before is called before the statement runs. The returned context is
+	// passed to the statement and After.
+	Before(ctx context.Context, e *QueryEvent) context.Context
+	// After is called once the statement has run.
+	After(ctx context.Context, e *QueryEvent)
+}
+
+type hooks []Hook
+
+// do runs f, reporting it to the hooks as e. The driver only runs a query
+// as its rows are read so a query that returns rows is reported once they
+// are closed.
+func (hh hooks) do(ctx context.Context, e *QueryEvent, f func(context.Context) error) error {
+	if len(hh) == 0 {
+		return f(ctx)
+	}
+	for _, h := range hh {
+		ctx = h.Before(ctx, e)
+	}
+	p := &pendingEvent{hooks: hh, ctx: ctx, e: e, t1: time.Now()}
+	if e.Op != OpQuery {
+		err := f(ctx)
+		p.finish(err)
+		return err
+	}
+	err := f(context.WithValue(ctx, pendingEventKey{}, p))
+	if err != nil || !p.claimed.Load() {
+		p.finish(err)
+	}
+	return err
+}
+
+type pendingEventKey struct{}
+
+// pendingEvent is an event which has not been reported to the hooks yet.
+type pendingEvent struct {
+	hooks   hooks
+	ctx     context.Context
+	e       *QueryEvent
+	t1      time.Time
+	claimed atomic.Bool // the event is finished when the rows are closed
+	once    sync.Once
+}
+
+// claim returns the pendingEvent of ctx if it was not already claimed by
+// other rows.
+func claim(ctx context.Context) (*pendingEvent, bool) {
+	p, ok := ctx.Value(pendingEventKey{}).(*pendingEvent)
+	if !ok || !p.claimed.CompareAndSwap(false, true) {
+		return nil, false
+	}
+	return p, true
+}
+
+// finish reports the event to the hooks.
+func (p *pendingEvent) finish(err error) {
+	p.once.Do(func() {
+		p.e.Duration, p.e.Err = time.Since(p.t1), err
+		for i := len(p.hooks) - 1; i >= 0; i-- {
+			p.hooks[i].After(p.ctx, p.e)
+		}
+	})
+}
+
+// SlowQueryLogger returns a [Hook] that logs statements taking at least d.
+// The logger for each statement is given by logger so that, for example,
+// hlog.FromContext can be used to include the attributes of the request.
+func SlowQueryLogger(d time.Duration, logger func(context.Context) *slog.Logger) Hook {
+	if logger == nil {
+		logger = func(context.Context) *slog.Logger { return slog.Default() }
+	}
+	return &slowQueryLogger{d: d, logger: logger}
+}
+
+type slowQueryLogger struct {
+	d      time.Duration
+	logger func(context.Context) *slog.Logger
+}
+
+func (l *slowQueryLogger) Before(ctx context.Context, e *QueryEvent) context.Context { return ctx }
+
+func (l *slowQueryLogger) After(ctx context.Context, e *QueryEvent) {
+	if e.Duration < l.d {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("pool", e.Pool),
+		slog.String("op", e.Op),
+		slog.String("query", e.Query),
+		slog.Duration("elapsed", e.Duration),
+	}
+	level := slog.LevelWarn
+	if e.Err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.Any("err", e.Err))
+	}
+	l.logger(ctx).LogAttrs(ctx, level, "Slow Query", attrs...)
+}
@@ -0,0 +1,81 @@
+package sql3_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+type testHook struct {
+	events    []string
+	durations []time.Duration
+}
+
+func (h *testHook) Before(ctx context.Context, e *QueryEvent) context.Context { return ctx }
+
+func (h *testHook) After(ctx context.Context, e *QueryEvent) {
+	h.events = append(h.events, e.Pool+" "+e.Op)
+	h.durations = append(h.durations, e.Duration)
+}
+
+func Test_WithHook(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		var h testHook
+		db, err := sqlFS.Up(context.TODO(), testFilename(t, "test.db"), WithHook(&h))
+		is.NoErr(err) // (sql3.FS).Up
+		t.Cleanup(func() { db.Close() })
+
+		err = db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			_, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "a", 1)
+			return err
+		})
+		is.NoErr(err) // (sql3.DB).DoTx
+
+		var n int
+		err = db.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+
+		is.Equal(h.events, []string{"write begin", "write exec", "write commit", "read query"})
+	})
+
+	t.Run("SlowRead", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		var h testHook
+		db, err := Open(testFilename(t, "test.db"),
+			WithHook(&h),
+			WithFunc("sleep", func(ms int) int { time.Sleep(time.Duration(ms) * time.Millisecond); return ms }, false),
+		)
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		var n int
+		err = db.QueryRow(context.TODO(), `select sleep(50)`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+
+		is.Equal(h.events, []string{"read query"})
+		is.True(h.durations[0] >= 50*time.Millisecond) // includes reading the rows
+	})
+
+	t.Run("SlowQueryLogger", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		var buf bytes.Buffer
+		l := slog.New(slog.NewTextHandler(&buf, nil))
+		db, err := Open(testFilename(t, "test.db"), WithHook(SlowQueryLogger(0, func(context.Context) *slog.Logger { return l })))
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		_, err = db.Exec(context.TODO(), `select 1`)
+		is.NoErr(err) // (sql3.DB).Exec
+		is.True(strings.Contains(buf.String(), `query="select 1"`))
+	})
+}
@@ -0,0 +1,247 @@
+// Package kv provides a typed key-value store backed by a [sql3.DB].
+//
+// Every [Store] shares a single table, each keeps its keys in its own bucket.
+// Writes take part in the [sql3.Tx] carried by their context, if any.
+package kv
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+
+	"go.adoublef.dev/sdk/database/sql3"
+	"go.adoublef.dev/sdk/errgroup"
+	"go.adoublef.dev/sdk/time/unix"
+)
+
+const table = "kv"
+
+// ErrNotFound is returned when a key does not exist or has expired.
+var ErrNotFound = errors.New("kv: key not found")
+
+// A Codec encodes values to and from the bytes that are stored.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// JSON encodes values with encoding/json.
+	JSON Codec = jsonCodec{}
+	// Gob encodes values with encoding/gob.
+	Gob Codec = gobCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// A Store maps string keys to values of type V.
+type Store[V any] struct {
+	db     *sql3.DB
+	bucket string
+	codec  Codec
+}
+
+// An Option configures a [Store].
+type Option func(*config)
+
+type config struct {
+	codec Codec
+}
+
+// WithCodec sets the [Codec] used to encode values, [JSON] by default.
+func WithCodec(c Codec) Option {
+	return func(cfg *config) { cfg.codec = c }
+}
+
+// New returns the store for bucket, creating the table if needed.
+func New[V any](ctx context.Context, db *sql3.DB, bucket string, opts ...Option) (*Store[V], error) {
+	c := &config{codec: JSON}
+	for _, opt := range opts {
+		opt(c)
+	}
+	for _, query := range []string{
+		`create table if not exists ` + table + ` (
+			bucket text not null,
+			key text not null,
+			value blob not null,
+			expires_at int,
+			primary key (bucket, key)
+		) strict, without rowid`,
+		`create index if not exists ` + table + `_expires_at on ` + table + ` (expires_at) where expires_at is not null`,
+	} {
+		if _, err := db.Exec(ctx, query); err != nil {
+			return nil, fmt.Errorf("create kv table: %w", err)
+		}
+	}
+	return &Store[V]{db: db, bucket: bucket, codec: c.codec}, nil
+}
+
+// Get returns the value of key, or [ErrNotFound].
+func (s *Store[V]) Get(ctx context.Context, key string) (V, error) {
+	var (
+		v    V
+		data []byte
+	)
+	err := s.db.Querier(ctx).QueryRow(ctx, `select value from `+table+`
+		where bucket = ? and key = ? and (expires_at is null or expires_at > ?)`, s.bucket, key, unix.Now()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return v, ErrNotFound
+	}
+	if err != nil {
+		return v, fmt.Errorf("get key %q: %w", key, err)
+	}
+	if err := s.codec.Unmarshal(data, &v); err != nil {
+		return v, fmt.Errorf("decode key %q: %w", key, err)
+	}
+	return v, nil
+}
+
+// A SetOption configures [Store.Set].
+type SetOption func(*unix.Time)
+
+// WithTTL expires the key d from now. Expired keys are not returned and are
+// removed by [Store.Sweep].
+func WithTTL(d time.Duration) SetOption {
+	return func(t *unix.Time) { *t = unix.FromTime(time.Now().Add(d)) }
+}
+
+// Set sets the value of key, replacing any previous value and expiry.
+func (s *Store[V]) Set(ctx context.Context, key string, v V, opts ...SetOption) error {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode key %q: %w", key, err)
+	}
+	var expires *unix.Time
+	for _, opt := range opts {
+		if expires == nil {
+			expires = new(unix.Time)
+		}
+		opt(expires)
+	}
+	_, err = s.db.Executor(ctx).Exec(ctx, `insert into `+table+` (bucket, key, value, expires_at) values (?, ?, ?, ?)
+		on conflict (bucket, key) do update set value = excluded.value, expires_at = excluded.expires_at`,
+		s.bucket, key, data, expires)
+	if err != nil {
+		return fmt.Errorf("set key %q: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes key. Deleting a key that does not exist is not an error.
+func (s *Store[V]) Delete(ctx context.Context, key string) error {
+	_, err := s.db.Executor(ctx).Exec(ctx, `delete from `+table+` where bucket = ? and key = ?`, s.bucket, key)
+	if err != nil {
+		return fmt.Errorf("delete key %q: %w", key, err)
+	}
+	return nil
+}
+
+// CompareAndSwap sets the value of key to new if its current value is old and
+// reports whether it did. Values are compared by their encoding, so the
+// [Codec] must encode equal values to the same bytes. The expiry of the key is
+// unchanged.
+func (s *Store[V]) CompareAndSwap(ctx context.Context, key string, old, new V) (bool, error) {
+	od, err := s.codec.Marshal(old)
+	if err != nil {
+		return false, fmt.Errorf("encode key %q: %w", key, err)
+	}
+	nd, err := s.codec.Marshal(new)
+	if err != nil {
+		return false, fmt.Errorf("encode key %q: %w", key, err)
+	}
+	res, err := s.db.Executor(ctx).Exec(ctx, `update `+table+` set value = ?
+		where bucket = ? and key = ? and value = ? and (expires_at is null or expires_at > ?)`,
+		nd, s.bucket, key, od, unix.Now())
+	if err != nil {
+		return false, fmt.Errorf("compare and swap key %q: %w", key, err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// An Entry is a key and its value.
+type Entry[V any] struct {
+	Key   string
+	Value V
+}
+
+// Scan returns an iterator over the entries whose keys start with prefix, in
+// key order. Keys are compared as bytes and must be valid UTF-8. If an error
+// occurs it is yielded as the final value.
+func (s *Store[V]) Scan(ctx context.Context, prefix string) iter.Seq2[Entry[V], error] {
+	return func(yield func(Entry[V], error) bool) {
+		type row struct {
+			Key   string `db:"key"`
+			Value []byte `db:"value"`
+		}
+		// no byte of a UTF-8 string is 0xff so every key with the prefix sorts
+		// before it
+		rows := sql3.QuerySeq[row](ctx, s.db.Querier(ctx), `select key, value from `+table+`
+			where bucket = ? and key >= ? and key < ? and (expires_at is null or expires_at > ?) order by key`,
+			s.bucket, prefix, prefix+"\xff", unix.Now())
+		for r, err := range rows {
+			if err != nil {
+				yield(Entry[V]{}, fmt.Errorf("scan prefix %q: %w", prefix, err))
+				return
+			}
+			e := Entry[V]{Key: r.Key}
+			if err := s.codec.Unmarshal(r.Value, &e.Value); err != nil {
+				yield(Entry[V]{}, fmt.Errorf("decode key %q: %w", r.Key, err))
+				return
+			}
+			if !yield(e, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Sweep removes the expired keys of every bucket and returns the number
+// removed.
+func (s *Store[V]) Sweep(ctx context.Context) (int64, error) {
+	res, err := s.db.Exec(ctx, `delete from `+table+` where expires_at <= ?`, unix.Now())
+	if err != nil {
+		return 0, fmt.Errorf("sweep expired keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// StartSweeper runs [Store.Sweep] in g every interval until the context of g
+// is done.
+func (s *Store[V]) StartSweeper(g *errgroup.Group, interval time.Duration) {
+	g.Go(func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if _, err := s.Sweep(ctx); err != nil && ctx.Err() == nil {
+					return err
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package kv_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go.adoublef.dev/is"
+	"go.adoublef.dev/sdk/database/sql3"
+	. "go.adoublef.dev/sdk/database/sql3/kv"
+	"go.adoublef.dev/sdk/database/sql3/sql3test"
+	"go.adoublef.dev/sdk/errgroup"
+)
+
+// sqlFS has no migrations as the tables are created by New.
+var sqlFS, _ = sql3.NewFS(fstest.MapFS{}, "")
+
+type testValue struct {
+	Name  string
+	Count int
+}
+
+func Test_Store(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		s, err := New[testValue](context.TODO(), db, "values")
+		is.NoErr(err) // kv.New
+
+		_, err = s.Get(context.TODO(), "a")
+		is.True(errors.Is(err, ErrNotFound)) // (kv.Store).Get
+
+		err = s.Set(context.TODO(), "a", testValue{"a", 1})
+		is.NoErr(err) // (kv.Store).Set
+
+		v, err := s.Get(context.TODO(), "a")
+		is.NoErr(err) // (kv.Store).Get
+		is.Equal(v, testValue{"a", 1})
+
+		err = s.Delete(context.TODO(), "a")
+		is.NoErr(err) // (kv.Store).Delete
+
+		_, err = s.Get(context.TODO(), "a")
+		is.True(errors.Is(err, ErrNotFound)) // (kv.Store).Get
+	})
+
+	t.Run("Gob", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		s, err := New[testValue](context.TODO(), db, "values", WithCodec(Gob))
+		is.NoErr(err) // kv.New
+
+		err = s.Set(context.TODO(), "a", testValue{"a", 1})
+		is.NoErr(err) // (kv.Store).Set
+
+		v, err := s.Get(context.TODO(), "a")
+		is.NoErr(err) // (kv.Store).Get
+		is.Equal(v, testValue{"a", 1})
+	})
+
+	t.Run("CompareAndSwap", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		s, err := New[int](context.TODO(), db, "counters")
+		is.NoErr(err) // kv.New
+
+		err = s.Set(context.TODO(), "n", 1)
+		is.NoErr(err) // (kv.Store).Set
+
+		ok, err := s.CompareAndSwap(context.TODO(), "n", 2, 3)
+		is.NoErr(err) // (kv.Store).CompareAndSwap
+		is.True(!ok)
+
+		ok, err = s.CompareAndSwap(context.TODO(), "n", 1, 2)
+		is.NoErr(err) // (kv.Store).CompareAndSwap
+		is.True(ok)
+
+		v, err := s.Get(context.TODO(), "n")
+		is.NoErr(err) // (kv.Store).Get
+		is.Equal(v, 2)
+	})
+
+	t.Run("Scan", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		s, err := New[int](context.TODO(), db, "counters")
+		is.NoErr(err) // kv.New
+		other, err := New[int](context.TODO(), db, "other")
+		is.NoErr(err) // kv.New
+
+		for i, key := range []string{"user/b", "user/a", "users", "team/a"} {
+			err := s.Set(context.TODO(), key, i)
+			is.NoErr(err) // (kv.Store).Set
+		}
+		err = other.Set(context.TODO(), "user/c", 0)
+		is.NoErr(err) // (kv.Store).Set
+
+		var keys []string
+		for e, err := range s.Scan(context.TODO(), "user/") {
+			is.NoErr(err) // (kv.Store).Scan
+			keys = append(keys, e.Key)
+		}
+		is.Equal(keys, []string{"user/a", "user/b"})
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		s, err := New[int](context.TODO(), db, "sessions")
+		is.NoErr(err) // kv.New
+
+		err = s.Set(context.TODO(), "a", 1, WithTTL(20*time.Millisecond))
+		is.NoErr(err) // (kv.Store).Set
+		err = s.Set(context.TODO(), "b", 2)
+		is.NoErr(err) // (kv.Store).Set
+
+		_, err = s.Get(context.TODO(), "a")
+		is.NoErr(err) // (kv.Store).Get
+
+		time.Sleep(30 * time.Millisecond)
+		_, err = s.Get(context.TODO(), "a")
+		is.True(errors.Is(err, ErrNotFound)) // (kv.Store).Get
+
+		n, err := s.Sweep(context.TODO())
+		is.NoErr(err) // (kv.Store).Sweep
+		is.Equal(n, int64(1))
+	})
+
+	t.Run("Sweeper", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		s, err := New[int](context.TODO(), db, "sessions")
+		is.NoErr(err) // kv.New
+
+		err = s.Set(context.TODO(), "a", 1, WithTTL(time.Millisecond))
+		is.NoErr(err) // (kv.Store).Set
+
+		ctx, cancel := context.WithCancel(context.Background())
+		g := errgroup.New(ctx)
+		s.StartSweeper(g, 5*time.Millisecond)
+
+		var n int
+		for range 100 {
+			err = db.QueryRow(context.TODO(), `select count(*) from kv`).Scan(&n)
+			is.NoErr(err) // (sql3.DB).QueryRow
+			if n == 0 {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		is.Equal(n, 0)
+
+		cancel()
+		is.NoErr(g.Wait()) // (errgroup.Group).Wait
+	})
+
+	t.Run("Tx", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		s, err := New[int](context.TODO(), db, "counters")
+		is.NoErr(err) // kv.New
+
+		errRollback := errors.New("rollback")
+		err = db.DoTx(context.TODO(), func(ctx context.Context, tx *sql3.Tx) error {
+			if err := s.Set(ctx, "n", 1); err != nil {
+				return err
+			}
+			// reads within the transaction see its writes
+			if _, err := s.Get(ctx, "n"); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		is.True(errors.Is(err, errRollback)) // (sql3.DB).DoTx
+
+		_, err = s.Get(context.TODO(), "n")
+		is.True(errors.Is(err, ErrNotFound)) // (kv.Store).Get
+	})
+}
@@ -0,0 +1,60 @@
+package sql3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// memoryID is used to name in-memory databases.
+var memoryID atomic.Uint64
+
+// memoryName returns the memdb name of the in-memory database called name.
+// A memdb database whose name begins with "/" is shared by every connection
+// in the process that opens it.
+func memoryName(name string) string {
+	if strings.HasPrefix(name, "/") {
+		return name
+	}
+	return "/" + name
+}
+
+// openMemory opens the in-memory database called name and those attached,
+// replacing the names of the attached databases with their memdb names. It
+// returns the memdb name of name and a connection to each database which
+// keeps it alive while the connections of the pools come and go.
+func (c *config) openMemory(name string) (string, []*sqlite3.SQLiteConn, error) {
+	var conns []*sqlite3.SQLiteConn
+	open := func(name string) (string, error) {
+		name = memoryName(name)
+		conn, err := (&sqlite3.SQLiteDriver{}).Open("file:" + name + "?vfs=memdb")
+		if err != nil {
+			return "", fmt.Errorf("open in-memory database: %w", err)
+		}
+		conns = append(conns, conn.(*sqlite3.SQLiteConn))
+		return name, nil
+	}
+	filename, err := open(name)
+	if err != nil {
+		return "", nil, err
+	}
+	for i, a := range c.attach {
+		if c.attach[i].filename, err = open(a.name); err != nil {
+			return "", nil, errors.Join(err, closeMemory(conns))
+		}
+	}
+	return filename, conns, nil
+}
+
+// closeMemory closes the connections which keep the in-memory databases
+// alive. A database is freed once no connection has it open.
+func closeMemory(conns []*sqlite3.SQLiteConn) error {
+	var errs []error
+	for _, conn := range conns {
+		errs = append(errs, conn.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,136 @@
+package sql3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+
+	"github.com/maragudk/migrate"
+)
+
+// migrationsTable is the table used by [migrate] to record the version.
+const migrationsTable = "migrations"
+
+var (
+	upMatcher   = regexp.MustCompile(`^([\w-]+).up.sql$`)
+	downMatcher = regexp.MustCompile(`^([\w-]+).down.sql`)
+)
+
+// Down from the current version, undoing every migration.
+func (fs *FS) Down(ctx context.Context, db *DB) error {
+	if err := fs.migrator(db).MigrateDown(ctx); err != nil {
+		return fmt.Errorf("running down migrations: %w", err)
+	}
+	db.feed.flush()
+	return nil
+}
+
+// To migrates up or down to the given version. An empty version undoes every
+// migration.
+func (fs *FS) To(ctx context.Context, db *DB, version string) error {
+	if err := fs.migrator(db).MigrateTo(ctx, version); err != nil {
+		return fmt.Errorf("running migrations to %q: %w", version, err)
+	}
+	db.feed.flush()
+	return nil
+}
+
+// Version returns the current migration version of db. An empty version is
+// returned if no migrations have been applied.
+func (fs *FS) Version(ctx context.Context, db *DB) (string, error) {
+	var exists bool
+	err := db.wc.QueryRowContext(ctx, `select exists (select 1 from sqlite_schema where type = 'table' and name = ?)`, migrationsTable).Scan(&exists)
+	if err != nil || !exists {
+		return "", err
+	}
+	var version string
+	if err := db.wc.QueryRowContext(ctx, `select version from `+migrationsTable).Scan(&version); err != nil {
+		return "", fmt.Errorf("get current migration version: %w", err)
+	}
+	return version, nil
+}
+
+// Pending returns the names of the migration files that [FS.Up] would apply
+// to db, without applying them.
+func (fs *FS) Pending(ctx context.Context, db *DB) ([]string, error) {
+	current, err := fs.Version(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	names, err := filenames(fs.fsys, upMatcher)
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, name := range names {
+		if version(upMatcher, name) > current {
+			pending = append(pending, name)
+		}
+	}
+	return pending, nil
+}
+
+// PendingTo returns the names of the migration files that [FS.To] would apply
+// to db in order, without applying them.
+func (fs *FS) PendingTo(ctx context.Context, db *DB, v string) ([]string, error) {
+	current, err := fs.Version(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if v == current {
+		return nil, nil
+	}
+	matcher := upMatcher
+	if v < current {
+		matcher = downMatcher
+	}
+	names, err := filenames(fs.fsys, matcher)
+	if err != nil {
+		return nil, err
+	}
+	if v != "" && !slices.ContainsFunc(names, func(name string) bool { return version(matcher, name) == v }) {
+		return nil, errors.New("finding version " + v)
+	}
+	var pending []string
+	for _, name := range names {
+		switch n := version(matcher, name); {
+		case v > current && n > current && n <= v:
+			pending = append(pending, name)
+		case v < current && n <= current && n > v:
+			pending = append(pending, name)
+		}
+	}
+	if v < current {
+		slices.Reverse(pending)
+	}
+	return pending, nil
+}
+
+// migrator returns a [migrate.Migrator] that records the checksum of each
+// migration applied to db.
+func (fs *FS) migrator(db *DB) *migrate.Migrator {
+	return migrate.New(migrate.Options{DB: db.wc, FS: fs.fsys, Table: migrationsTable, After: fs.record})
+}
+
+// filenames returns the names of the files matching the matcher in
+// alphabetical order.
+func filenames(fsys fs.FS, matcher *regexp.Regexp) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if matcher.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func version(matcher *regexp.Regexp, name string) string {
+	return matcher.ReplaceAllString(name, "$1")
+}
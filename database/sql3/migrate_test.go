@@ -0,0 +1,65 @@
+package sql3_test
+
+import (
+	"context"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_FS_To(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		v, err := sqlFS.Version(context.TODO(), db)
+		is.NoErr(err) // (sql3.FS).Version
+		is.Equal(v, "1")
+
+		pending, err := sqlFS.PendingTo(context.TODO(), db, "0")
+		is.NoErr(err) // (sql3.FS).PendingTo
+		is.Equal(pending, []string{"1.down.sql"})
+
+		err = sqlFS.To(context.TODO(), db, "0")
+		is.NoErr(err) // (sql3.FS).To
+
+		v, err = sqlFS.Version(context.TODO(), db)
+		is.NoErr(err) // (sql3.FS).Version
+		is.Equal(v, "0")
+
+		pending, err = sqlFS.Pending(context.TODO(), db)
+		is.NoErr(err) // (sql3.FS).Pending
+		is.Equal(pending, []string{"1.up.sql"})
+	}))
+
+	t.Run("Down", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		pending, err := sqlFS.PendingTo(context.TODO(), db, "")
+		is.NoErr(err) // (sql3.FS).PendingTo
+		is.Equal(pending, []string{"1.down.sql", "0.down.sql"})
+
+		err = sqlFS.Down(context.TODO(), db)
+		is.NoErr(err) // (sql3.FS).Down
+
+		v, err := sqlFS.Version(context.TODO(), db)
+		is.NoErr(err) // (sql3.FS).Version
+		is.Equal(v, "")
+
+		pending, err = sqlFS.Pending(context.TODO(), db)
+		is.NoErr(err) // (sql3.FS).Pending
+		is.Equal(pending, []string{"0.up.sql", "1.up.sql"})
+	}))
+
+	t.Run("Version", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := Open(testFilename(t, "test.db"))
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		v, err := sqlFS.Version(context.TODO(), db)
+		is.NoErr(err) // (sql3.FS).Version
+		is.Equal(v, "")
+	})
+}
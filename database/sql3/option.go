@@ -0,0 +1,150 @@
+package sql3
+
+import (
+	"io/fs"
+	"runtime"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// An Option configures a [DB] returned from [Open].
+type Option func(*config)
+
+type config struct {
+	pragma          map[string]string
+	readConns       int
+	writeConns      int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+	dirMode         fs.FileMode
+	memory          bool
+	readOnly        bool
+	retry           RetryPolicy
+	stmtCacheSize   int
+	hooks           hooks
+	checkpoint      CheckpointPolicy
+	register        []func(*sqlite3.SQLiteConn) error
+	attach          []attachment
+}
+
+func newConfig(opts ...Option) *config {
+	c := &config{
+		pragma:     pragma(),
+		readConns:  max(4, runtime.NumCPU()),
+		writeConns: 1,
+		dirMode:    0755,
+		retry:      DefaultRetryPolicy,
+		checkpoint: DefaultCheckpointPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithPragma sets the value of a PRAGMA on every connection, overriding the
+// default. The name is given without the "PRAGMA" keyword, e.g. "cache_size".
+// An empty value removes the PRAGMA.
+func WithPragma(name, value string) Option {
+	return func(c *config) {
+		if value == "" {
+			delete(c.pragma, name)
+			return
+		}
+		c.pragma[name] = value
+	}
+}
+
+// WithReadConns sets the maximum number of open connections in the read pool.
+func WithReadConns(n int) Option {
+	return func(c *config) { c.readConns = n }
+}
+
+// WithWriteConns sets the maximum number of open connections in the write
+// pool. SQLite only allows a single writer so values other than 1 will
+// usually result in busy errors.
+func WithWriteConns(n int) Option {
+	return func(c *config) { c.writeConns = n }
+}
+
+// WithConnMaxLifetime sets the maximum amount of time a connection in either
+// pool may be reused.
+func WithConnMaxLifetime(d time.Duration) Option {
+	return func(c *config) { c.connMaxLifetime = d }
+}
+
+// WithConnMaxIdleTime sets the maximum amount of time a connection in either
+// pool may be idle.
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return func(c *config) { c.connMaxIdleTime = d }
+}
+
+// WithDirMode sets the permissions used when creating the directory of the
+// database file.
+func WithDirMode(mode fs.FileMode) Option {
+	return func(c *config) { c.dirMode = mode }
+}
+
+// WithMemory opens an in-memory database using the memdb VFS. The filename
+// names the database so that both pools share the same data, if it is empty a
+// unique name is used. The data is lost when the last [DB] using the name is
+// closed.
+//
+// In-memory databases do not support WAL. While a write transaction is open
+// reads on the read pool wait for it, up to busy_timeout, and the writer
+// waits for open reads before it can commit. Within [DB.DoTx] read through
+// the [Tx] rather than the [DB].
+func WithMemory() Option {
+	return func(c *config) {
+		c.memory = true
+		delete(c.pragma, "journal_mode")
+	}
+}
+
+// WithReadOnly opens the database in read-only mode. Any attempt to write
+// returns an error.
+func WithReadOnly() Option {
+	return func(c *config) {
+		c.readOnly = true
+		// changing the journal mode is a write
+		delete(c.pragma, "journal_mode")
+	}
+}
+
+// WithRetryPolicy sets the [RetryPolicy] of the [DB].
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *config) { c.retry = p }
+}
+
+// WithStmtCache caches up to n prepared statements for each pool, keyed by
+// query text. The least recently used statement is closed when the cache is
+// full. Transactions reuse statements already in the cache.
+func WithStmtCache(n int) Option {
+	return func(c *config) { c.stmtCacheSize = n }
+}
+
+// WithHook adds a [Hook] that observes every statement run against the [DB],
+// including the beginning and end of transactions.
+func WithHook(h Hook) Option {
+	return func(c *config) { c.hooks = append(c.hooks, h) }
+}
+
+// WithCheckpoint sets the [CheckpointPolicy] of the background checkpointer
+// which runs until the [DB] is closed, replacing [DefaultCheckpointPolicy].
+// A policy with a zero Interval disables the checkpointer.
+func WithCheckpoint(p CheckpointPolicy) Option {
+	return func(c *config) { c.checkpoint = p }
+}
+
+func pragma() map[string]string {
+	return map[string]string{
+		"journal_mode": "wal",
+		"busy_timeout": "5000",
+		"synchronous":  "normal",
+		"cache_size":   "1000000000",
+		"foreign_keys": "true",
+		"temp_store":   "memory",
+		"mmap_size":    "3000000000",
+	}
+}
@@ -0,0 +1,69 @@
+package sql3_test
+
+import (
+	"context"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_Open(t *testing.T) {
+	t.Run("WithPragma", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := Open(testFilename(t, "test.db"), WithPragma("cache_size", "-2000"))
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		var n int
+		err = db.QueryRow(context.TODO(), `pragma cache_size`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, -2000)
+	})
+
+	t.Run("WithMemory", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := sqlFS.Up(context.TODO(), t.Name(), WithMemory(), WithPragma("busy_timeout", "10"))
+		is.NoErr(err) // (sql3.FS).Up
+		t.Cleanup(func() { db.Close() })
+
+		_, err = db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "a", 1)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		var n int
+		err = db.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 1)
+
+		// reads on the read pool wait for an open write transaction
+		tx, err := db.Tx(context.TODO())
+		is.NoErr(err) // (sql3.DB).Tx
+		_, err = tx.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "b", 2)
+		is.NoErr(err) // (sql3.Tx).Exec
+		err = db.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+		is.True(IsBusy(err))  // (sql3.DB).QueryRow
+		is.NoErr(tx.Commit()) // (sql3.Tx).Commit
+
+		err = db.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+		is.NoErr(err) // (sql3.DB).QueryRow
+		is.Equal(n, 2)
+	})
+
+	t.Run("WithReadOnly", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		filename := testFilename(t, "test.db")
+		db, err := sqlFS.Up(context.TODO(), filename)
+		is.NoErr(err) // (sql3.FS).Up
+		is.NoErr(db.Close())
+
+		db, err = Open(filename, WithReadOnly())
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		_, err = db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "a", 1)
+		is.True(err != nil) // (sql3.DB).Exec
+	})
+}
@@ -0,0 +1,299 @@
+// Package queue provides a durable job queue stored in a [sql3.DB].
+//
+// Jobs are leased in batches by a single poller for each [Queue] and handed to
+// its workers, so that only the poller and the completion of each job write to
+// the database and workers do not contend for the write connection.
+package queue
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"go.adoublef.dev/sdk/database/sql3"
+	"go.adoublef.dev/sdk/errgroup"
+	"go.adoublef.dev/sdk/time/unix"
+)
+
+const table = "queue_jobs"
+
+var nameMatcher = regexp.MustCompile(`^\w+$`)
+
+// A Job is a unit of work leased from a [Queue].
+type Job struct {
+	ID        int64     `db:"id"`
+	Queue     string    `db:"queue"`
+	Payload   []byte    `db:"payload"`
+	Attempt   int       `db:"attempts"`   // Number of times the job has been leased, starting at 1.
+	LastError string    `db:"last_error"` // Error returned by the last attempt.
+	CreatedAt unix.Time `db:"created_at"`
+}
+
+// A Handler processes a job. If it returns an error the job is retried after
+// a backoff, or moved to the dead letters once it has been attempted the
+// maximum number of times. The context is cancelled once the lease of the job
+// expires.
+type Handler func(ctx context.Context, job *Job) error
+
+// A Queue is a named queue of jobs.
+type Queue struct {
+	db   *sql3.DB
+	name string
+	c    *config
+}
+
+// An Option configures a [Queue].
+type Option func(*config)
+
+type config struct {
+	visibility   time.Duration
+	maxAttempts  int
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+}
+
+// WithVisibilityTimeout sets how long a job is leased to a worker. If the job
+// has not completed by then it is leased again.
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(c *config) { c.visibility = d }
+}
+
+// WithMaxAttempts sets the number of times a job is attempted before it is
+// moved to the dead letters.
+func WithMaxAttempts(n int) Option {
+	return func(c *config) { c.maxAttempts = n }
+}
+
+// WithBackoff sets the delay before a failed job is retried. The delay doubles
+// from min with each attempt, up to max.
+func WithBackoff(min, max time.Duration) Option {
+	return func(c *config) { c.minBackoff, c.maxBackoff = min, max }
+}
+
+// WithPollInterval sets how often the queue is polled for jobs that have
+// become ready. Jobs enqueued through the same [sql3.DB] are picked up as soon
+// as their transaction commits.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *config) { c.pollInterval = d }
+}
+
+// New returns the queue called name, creating the table of jobs if needed.
+func New(ctx context.Context, db *sql3.DB, name string, opts ...Option) (*Queue, error) {
+	c := &config{
+		visibility:   30 * time.Second,
+		maxAttempts:  5,
+		minBackoff:   time.Second,
+		maxBackoff:   5 * time.Minute,
+		pollInterval: time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if !nameMatcher.MatchString(name) {
+		return nil, fmt.Errorf("queue: invalid name %q", name)
+	}
+	for _, query := range []string{
+		`create table if not exists ` + table + ` (
+			id integer primary key,
+			queue text not null,
+			payload blob not null,
+			attempts int not null default 0,
+			run_at int not null,
+			created_at int not null,
+			last_error text not null default '',
+			dead int not null default 0
+		) strict`,
+		`create index if not exists ` + table + `_ready on ` + table + ` (queue, dead, run_at)`,
+	} {
+		if _, err := db.Exec(ctx, query); err != nil {
+			return nil, fmt.Errorf("create queue table: %w", err)
+		}
+	}
+	return &Queue{db: db, name: name, c: c}, nil
+}
+
+// An EnqueueOption configures [Queue.Enqueue].
+type EnqueueOption func(*time.Time)
+
+// WithDelay schedules the job to run no earlier than d from now.
+func WithDelay(d time.Duration) EnqueueOption {
+	return func(t *time.Time) { *t = time.Now().Add(d) }
+}
+
+// WithRunAt schedules the job to run no earlier than t.
+func WithRunAt(t time.Time) EnqueueOption {
+	return func(at *time.Time) { *at = t }
+}
+
+// Enqueue adds a job to the queue and returns its ID. If ctx carries a
+// [sql3.Tx] the job is enqueued in that transaction, so it only becomes
+// visible once the transaction commits. See [sql3.NewContext].
+func (q *Queue) Enqueue(ctx context.Context, payload []byte, opts ...EnqueueOption) (int64, error) {
+	now := time.Now()
+	runAt := now
+	for _, opt := range opts {
+		opt(&runAt)
+	}
+	if payload == nil {
+		payload = []byte{}
+	}
+	res, err := q.db.Executor(ctx).Exec(ctx, `insert into `+table+` (queue, payload, run_at, created_at) values (?, ?, ?, ?)`,
+		q.name, payload, unix.FromTime(runAt), unix.FromTime(now))
+	if err != nil {
+		return 0, fmt.Errorf("enqueue job: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// Start runs the poller and workers of the queue in g until the context of g
+// is done. Each job is passed to h by one of the workers.
+func (q *Queue) Start(g *errgroup.Group, workers int, h Handler) {
+	var (
+		jobs = make(chan *Job)
+		idle atomic.Int64
+	)
+	idle.Store(int64(workers))
+	g.Go(func(ctx context.Context) error {
+		defer close(jobs)
+		return q.poll(ctx, jobs, &idle)
+	})
+	for range workers {
+		g.Go(func(ctx context.Context) error {
+			for job := range jobs {
+				if err := q.handle(ctx, job, h); err != nil {
+					return err
+				}
+				idle.Add(1)
+			}
+			return nil
+		})
+	}
+}
+
+// poll leases jobs for the idle workers until ctx is done.
+func (q *Queue) poll(ctx context.Context, jobs chan<- *Job, idle *atomic.Int64) error {
+	sub := q.db.Subscribe(1, table)
+	defer sub.Close()
+	ticker := time.NewTicker(q.c.pollInterval)
+	defer ticker.Stop()
+	for {
+		if n := idle.Load(); n > 0 {
+			leased, err := q.lease(ctx, int(n))
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			idle.Add(-int64(len(leased)))
+			for i := range leased {
+				select {
+				case jobs <- &leased[i]:
+				case <-ctx.Done():
+					// unsent jobs are leased again once their lease expires
+					return nil
+				}
+			}
+			if len(leased) == int(n) {
+				continue
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.C():
+		case <-ticker.C:
+		}
+	}
+}
+
+// lease leases up to n ready jobs, moving jobs out of attempts to the dead
+// letters.
+func (q *Queue) lease(ctx context.Context, n int) (jobs []Job, err error) {
+	now := time.Now().UnixMilli()
+	err = q.db.DoTx(ctx, func(ctx context.Context, tx *sql3.Tx) error {
+		// jobs whose last lease expired without completing
+		_, err := tx.Exec(ctx, `update `+table+` set dead = 1, last_error = 'visibility timeout exceeded'
+			where queue = ? and dead = 0 and run_at <= ? and attempts >= ?`, q.name, now, q.c.maxAttempts)
+		if err != nil {
+			return err
+		}
+		jobs, err = sql3.QueryAll[Job](ctx, tx, `update `+table+` set attempts = attempts + 1, run_at = ?
+			where id in (select id from `+table+` where queue = ? and dead = 0 and run_at <= ? order by run_at, id limit ?)
+			returning id, queue, payload, attempts, last_error, created_at`,
+			now+q.c.visibility.Milliseconds(), q.name, now, n)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lease jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// handle runs h and records the outcome of the job.
+func (q *Queue) handle(ctx context.Context, job *Job, h Handler) error {
+	deadline := time.Now().Add(q.c.visibility)
+	hctx, cancel := context.WithDeadline(ctx, deadline)
+	err := h(hctx, job)
+	cancel()
+	if err != nil && ctx.Err() != nil {
+		// stopped by shutdown, the lease expires and the job runs again
+		return nil
+	}
+	// record the outcome even if shutdown has begun
+	ctx = context.WithoutCancel(ctx)
+	// the job may have been leased again if the handler overran, in which case
+	// the attempts no longer match and the outcome is ignored
+	switch {
+	case err == nil:
+		_, err = q.db.Exec(ctx, `delete from `+table+` where id = ? and attempts = ?`, job.ID, job.Attempt)
+	case job.Attempt >= q.c.maxAttempts:
+		_, err = q.db.Exec(ctx, `update `+table+` set dead = 1, last_error = ? where id = ? and attempts = ?`,
+			err.Error(), job.ID, job.Attempt)
+	default:
+		runAt := time.Now().Add(q.backoff(job.Attempt)).UnixMilli()
+		_, err = q.db.Exec(ctx, `update `+table+` set run_at = ?, last_error = ? where id = ? and attempts = ?`,
+			runAt, err.Error(), job.ID, job.Attempt)
+	}
+	if err != nil {
+		return fmt.Errorf("complete job %d: %w", job.ID, err)
+	}
+	return nil
+}
+
+// backoff returns the delay before the attempt after n.
+func (q *Queue) backoff(n int) time.Duration {
+	d := q.c.minBackoff
+	for i := 1; i < n && d < q.c.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, q.c.maxBackoff)
+}
+
+// Dead returns the jobs that have been moved to the dead letters, oldest
+// first.
+func (q *Queue) Dead(ctx context.Context) ([]Job, error) {
+	jobs, err := sql3.QueryAll[Job](ctx, q.db, `select id, queue, payload, attempts, last_error, created_at from `+table+`
+		where queue = ? and dead = 1 order by id`, q.name)
+	if err != nil {
+		return nil, fmt.Errorf("dead jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// Requeue moves a job from the dead letters back to the queue, with its
+// attempts reset.
+func (q *Queue) Requeue(ctx context.Context, id int64) error {
+	res, err := q.db.Exec(ctx, `update `+table+` set dead = 0, attempts = 0, run_at = ? where id = ? and queue = ? and dead = 1`,
+		time.Now().UnixMilli(), id, q.name)
+	if err != nil {
+		return fmt.Errorf("requeue job: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("requeue job %d: not a dead job", id)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go.adoublef.dev/is"
+	"go.adoublef.dev/sdk/database/sql3"
+	. "go.adoublef.dev/sdk/database/sql3/queue"
+	"go.adoublef.dev/sdk/database/sql3/sql3test"
+	"go.adoublef.dev/sdk/errgroup"
+)
+
+// sqlFS has no migrations as the tables are created by New.
+var sqlFS, _ = sql3.NewFS(fstest.MapFS{}, "")
+
+func Test_Queue(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		q, err := New(context.TODO(), db, "emails", WithPollInterval(10*time.Millisecond))
+		is.NoErr(err) // queue.New
+
+		for range 10 {
+			_, err := q.Enqueue(context.TODO(), []byte("hello"))
+			is.NoErr(err) // (queue.Queue).Enqueue
+		}
+
+		done := make(chan *Job, 10)
+		wait := testStart(t, q, 3, func(ctx context.Context, job *Job) error {
+			done <- job
+			return nil
+		})
+		for range 10 {
+			job := testRecv(t, done)
+			is.Equal(job.Payload, []byte("hello"))
+			is.Equal(job.Attempt, 1)
+		}
+		is.NoErr(wait()) // (errgroup.Group).Wait
+	})
+
+	t.Run("Tx", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		q, err := New(context.TODO(), db, "emails", WithPollInterval(time.Hour))
+		is.NoErr(err) // queue.New
+
+		errRollback := errors.New("rollback")
+		err = db.DoTx(context.TODO(), func(ctx context.Context, tx *sql3.Tx) error {
+			_, err := q.Enqueue(ctx, []byte("rolled back"))
+			is.NoErr(err) // (queue.Queue).Enqueue
+			return errRollback
+		})
+		is.True(errors.Is(err, errRollback)) // (sql3.DB).DoTx
+
+		done := make(chan *Job, 1)
+		wait := testStart(t, q, 1, func(ctx context.Context, job *Job) error {
+			done <- job
+			return nil
+		})
+		// committing wakes the poller without waiting for the poll interval
+		err = db.DoTx(context.TODO(), func(ctx context.Context, tx *sql3.Tx) error {
+			_, err := q.Enqueue(ctx, []byte("committed"))
+			return err
+		})
+		is.NoErr(err) // (sql3.DB).DoTx
+
+		is.Equal(testRecv(t, done).Payload, []byte("committed"))
+		is.NoErr(wait()) // (errgroup.Group).Wait
+	})
+
+	t.Run("Dead", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		q, err := New(context.TODO(), db, "emails",
+			WithPollInterval(5*time.Millisecond), WithMaxAttempts(3), WithBackoff(time.Millisecond, time.Millisecond))
+		is.NoErr(err) // queue.New
+
+		id, err := q.Enqueue(context.TODO(), []byte("fail"))
+		is.NoErr(err) // (queue.Queue).Enqueue
+
+		done := make(chan *Job, 3)
+		wait := testStart(t, q, 1, func(ctx context.Context, job *Job) error {
+			done <- job
+			return errors.New("failed")
+		})
+		for i := range 3 {
+			is.Equal(testRecv(t, done).Attempt, i+1)
+		}
+		is.NoErr(wait()) // (errgroup.Group).Wait
+
+		dead, err := q.Dead(context.TODO())
+		is.NoErr(err) // (queue.Queue).Dead
+		is.Equal(len(dead), 1)
+		is.Equal(dead[0].ID, id)
+		is.Equal(dead[0].LastError, "failed")
+
+		err = q.Requeue(context.TODO(), id)
+		is.NoErr(err) // (queue.Queue).Requeue
+
+		dead, err = q.Dead(context.TODO())
+		is.NoErr(err) // (queue.Queue).Dead
+		is.Equal(len(dead), 0)
+	})
+
+	t.Run("Scheduled", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		q, err := New(context.TODO(), db, "emails", WithPollInterval(5*time.Millisecond))
+		is.NoErr(err) // queue.New
+
+		start := time.Now()
+		_, err = q.Enqueue(context.TODO(), []byte("later"), WithDelay(100*time.Millisecond))
+		is.NoErr(err) // (queue.Queue).Enqueue
+
+		done := make(chan *Job, 1)
+		wait := testStart(t, q, 1, func(ctx context.Context, job *Job) error {
+			done <- job
+			return nil
+		})
+		testRecv(t, done)
+		is.True(time.Since(start) >= 100*time.Millisecond)
+		is.NoErr(wait()) // (errgroup.Group).Wait
+	})
+
+	t.Run("VisibilityTimeout", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db := sql3test.Up(t, sqlFS)
+
+		q, err := New(context.TODO(), db, "emails", WithPollInterval(5*time.Millisecond), WithVisibilityTimeout(50*time.Millisecond))
+		is.NoErr(err) // queue.New
+
+		_, err = q.Enqueue(context.TODO(), []byte("slow"))
+		is.NoErr(err) // (queue.Queue).Enqueue
+
+		var calls atomic.Int32
+		done := make(chan *Job, 1)
+		wait := testStart(t, q, 2, func(ctx context.Context, job *Job) error {
+			if calls.Add(1) == 1 {
+				// overrun the lease so the job is leased again
+				<-ctx.Done()
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			}
+			done <- job
+			return nil
+		})
+		is.Equal(testRecv(t, done).Attempt, 2)
+		is.NoErr(wait()) // (errgroup.Group).Wait
+	})
+}
+
+// testStart starts the workers of q and returns a function which stops them.
+func testStart(t *testing.T, q *Queue, workers int, h Handler) func() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := errgroup.New(ctx)
+	q.Start(g, workers, h)
+	t.Cleanup(cancel)
+	return func() error {
+		cancel()
+		return g.Wait()
+	}
+}
+
+func testRecv(t *testing.T, c <-chan *Job) *Job {
+	t.Helper()
+	select {
+	case job := <-c:
+		return job
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for job")
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package sql3
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DefaultRetryPolicy is the [RetryPolicy] used by a [DB] returned from [Open].
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	MinBackoff:  10 * time.Millisecond,
+	MaxBackoff:  500 * time.Millisecond,
+}
+
+// RetryPolicy describes how operations that fail with SQLITE_BUSY or
+// SQLITE_LOCKED are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times an operation is run.
+	// A value less than 2 disables retries.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry. A value of zero
+	// retries without delay.
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+	// OnRetry, if set, is called before each retry with the attempt that
+	// failed and its error.
+	OnRetry func(attempt int, err error)
+}
+
+// backoff returns the delay before retrying after the n-th attempt. Delays
+// grow exponentially and are jittered so that writers do not retry in step.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	if p.MinBackoff <= 0 {
+		return 0
+	}
+	d := p.MinBackoff << (n - 1)
+	if n > 63 || d>>(n-1) != p.MinBackoff {
+		// the shift overflowed
+		d = math.MaxInt64
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// do runs f until it succeeds, returns an error that is not busy or locked,
+// the attempts are exhausted or ctx is done.
+func (p RetryPolicy) do(ctx context.Context, f func() error) error {
+	for n := 1; ; n++ {
+		err := f()
+		if err == nil || n >= p.MaxAttempts || !IsBusy(err) {
+			return err
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(n, err)
+		}
+		t := time.NewTimer(p.backoff(n))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+// IsBusy reports whether err is a SQLITE_BUSY or SQLITE_LOCKED error.
+func IsBusy(err error) bool {
+	var serr sqlite3.Error
+	if !errors.As(err, &serr) {
+		return false
+	}
+	return serr.Code == sqlite3.ErrBusy || serr.Code == sqlite3.ErrLocked
+}
@@ -0,0 +1,189 @@
+package sql3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// QueryAll executes a query and scans every row into a T.
+//
+// If T is a struct that does not implement [sql.Scanner] columns are matched
+// to fields by their "db" tag, or their name if the field has no tag. A tag of
+// "-" ignores the field. Otherwise the query must return a single column which
+// is scanned into T.
+func QueryAll[T any](ctx context.Context, q Querier, query string, args ...any) ([]T, error) {
+	var vv []T
+	for v, err := range QuerySeq[T](ctx, q, query, args...) {
+		if err != nil {
+			return nil, err
+		}
+		vv = append(vv, v)
+	}
+	return vv, nil
+}
+
+// QueryOne executes a query and scans the first row into a T, in the same way
+// as [QueryAll]. If the query returns no rows [sql.ErrNoRows] is returned.
+func QueryOne[T any](ctx context.Context, q Querier, query string, args ...any) (T, error) {
+	for v, err := range QuerySeq[T](ctx, q, query, args...) {
+		return v, err
+	}
+	var zero T
+	return zero, sql.ErrNoRows
+}
+
+// QuerySeq executes a query and returns an iterator over its rows scanned into
+// a T, in the same way as [QueryAll]. The rows are closed once the iteration
+// stops. If an error occurs it is yielded as the final value.
+func QuerySeq[T any](ctx context.Context, q Querier, query string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		rows, err := q.Query(ctx, query, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+		scan, err := scanner[T](rows)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for rows.Next() {
+			var v T
+			if err := rows.Scan(scan(&v)...); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// scanner returns a function which gives the destinations for [sql.Rows.Scan]
+// for the columns of rows.
+func scanner[T any](rows *sql.Rows) (func(*T) []any, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	typ := reflect.TypeFor[T]()
+	if !isStruct(typ) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("sql3: scanning %d columns into %v", len(columns), typ)
+		}
+		return func(v *T) []any { return []any{v} }, nil
+	}
+	fields := structFields(typ)
+	index := make([][]int, len(columns))
+	for i, c := range columns {
+		idx, ok := fields[strings.ToLower(c)]
+		if !ok {
+			return nil, fmt.Errorf("sql3: missing field for column %q in %v", c, typ)
+		}
+		index[i] = idx
+	}
+	return func(v *T) []any {
+		rv := reflect.ValueOf(v).Elem()
+		dest := make([]any, len(index))
+		for i, idx := range index {
+			dest[i] = rv.FieldByIndex(idx).Addr().Interface()
+		}
+		return dest
+	}, nil
+}
+
+var scannerType = reflect.TypeFor[sql.Scanner]()
+
+// isStruct reports whether typ should have its columns mapped to fields.
+func isStruct(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && !reflect.PointerTo(typ).Implements(scannerType)
+}
+
+var fieldCache sync.Map // map[reflect.Type]*structInfo
+
+// structInfo describes how the fields of a struct map to columns.
+type structInfo struct {
+	fields  map[string][]int // field index by lowercase column name
+	columns []column         // in field order
+}
+
+// A column is a field of a struct mapped to a column.
+type column struct {
+	name    string
+	index   []int
+	key     bool // part of the primary key
+	auto    bool // assigned by the database
+	version bool // incremented by each update
+}
+
+// structFields returns the index of the fields of typ keyed by lowercase
+// column name. Fields of embedded structs are included.
+func structFields(typ reflect.Type) map[string][]int {
+	return structColumns(typ).fields
+}
+
+// structColumns returns the columns of typ in field order. The "db" tag may
+// be followed by the options "key", "auto" and "version", e.g.
+// `db:"id,key,auto"`.
+func structColumns(typ reflect.Type) *structInfo {
+	if f, ok := fieldCache.Load(typ); ok {
+		return f.(*structInfo)
+	}
+	columns := make(map[string]column)
+	var walk func(typ reflect.Type, index []int)
+	walk = func(typ reflect.Type, index []int) {
+		for i := range typ.NumField() {
+			f := typ.Field(i)
+			tag, ok := f.Tag.Lookup("db")
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "-" || (!f.IsExported() && !f.Anonymous) {
+				continue
+			}
+			idx := append(index[:len(index):len(index)], i)
+			if f.Anonymous && !ok && isStruct(f.Type) {
+				walk(f.Type, idx)
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			name = strings.ToLower(name)
+			// shallower fields take precedence, as with Go's selectors
+			if old, ok := columns[name]; !ok || len(old.index) > len(idx) {
+				c := column{name: name, index: idx}
+				for _, opt := range strings.Split(opts, ",") {
+					switch opt {
+					case "key":
+						c.key = true
+					case "auto":
+						c.auto = true
+					case "version":
+						c.version = true
+					}
+				}
+				columns[name] = c
+			}
+		}
+	}
+	walk(typ, nil)
+	info := &structInfo{fields: make(map[string][]int, len(columns))}
+	for name, c := range columns {
+		info.fields[name] = c.index
+		info.columns = append(info.columns, c)
+	}
+	slices.SortFunc(info.columns, func(a, b column) int { return slices.Compare(a.index, b.index) })
+	fieldCache.Store(typ, info)
+	return info
+}
@@ -0,0 +1,90 @@
+package sql3_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+	"go.adoublef.dev/sdk/time/date"
+	"go.adoublef.dev/sdk/time/julian"
+	"go.adoublef.dev/sdk/time/unix"
+)
+
+type testRow struct {
+	ID      string `db:"id"`
+	Counter int    `db:"counter"`
+}
+
+func Test_QueryAll(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		for i, id := range []string{"a", "b", "c"} {
+			_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, id, i)
+			is.NoErr(err) // (sql3.DB).Exec
+		}
+
+		rows, err := QueryAll[testRow](context.TODO(), db, `select id, counter from tests order by id`)
+		is.NoErr(err) // sql3.QueryAll
+		is.Equal(rows, []testRow{{"a", 0}, {"b", 1}, {"c", 2}})
+
+		ids, err := QueryAll[string](context.TODO(), db, `select id from tests order by id`)
+		is.NoErr(err) // sql3.QueryAll
+		is.Equal(ids, []string{"a", "b", "c"})
+	}))
+
+	t.Run("Time", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		type row struct {
+			Date   date.Date
+			Unix   unix.Time
+			Julian julian.Time
+		}
+
+		r, err := QueryOne[row](context.TODO(), db, `select '2016-10-18' as date, 1476748800000 as unix, julianday('2016-10-18') as julian`)
+		is.NoErr(err) // sql3.QueryOne
+		is.Equal(r.Date, date.Date{Year: 2016, Month: date.October, Day: 18})
+		is.Equal(r.Unix, unix.Time(1476748800000))
+		is.Equal(r.Julian.String(), "2016-10-18T00:00:00Z")
+
+		d, err := QueryOne[date.Date](context.TODO(), db, `select '2016-10-18'`)
+		is.NoErr(err) // sql3.QueryOne
+		is.Equal(d, r.Date)
+	}))
+
+	t.Run("ErrNoRows", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := QueryOne[testRow](context.TODO(), db, `select id, counter from tests`)
+		is.Err(err, sql.ErrNoRows) // sql3.QueryOne
+	}))
+}
+
+func Test_QuerySeq(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		err := db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			for i := range 10 {
+				if _, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, i, i); err != nil {
+					return err
+				}
+			}
+			var n int
+			for r, err := range QuerySeq[testRow](ctx, tx, `select id, counter from tests order by counter`) {
+				if err != nil {
+					return err
+				}
+				if n++; r.Counter == 4 {
+					break
+				}
+			}
+			is.Equal(n, 5)
+			return nil
+		})
+		is.NoErr(err) // (sql3.DB).DoTx
+	}))
+}
@@ -0,0 +1,218 @@
+package sql3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// A Schema describes the tables and triggers of a database.
+type Schema struct {
+	Tables   []Table
+	Triggers []Trigger
+}
+
+// A Table describes a table and its columns, indexes and foreign keys.
+type Table struct {
+	Name        string
+	SQL         string
+	Columns     []Column
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+}
+
+// A Column describes a column of a table.
+type Column struct {
+	Name    string         `db:"name"`
+	Type    string         `db:"type"`
+	NotNull bool           `db:"notnull"`
+	Default sql.NullString `db:"dflt_value"` // SQL text of the default value.
+	PK      int            `db:"pk"`         // Position in the primary key, 0 if not part of it.
+}
+
+// An Index describes an index of a table.
+type Index struct {
+	Name    string   `db:"name"`
+	Unique  bool     `db:"unique"`
+	Origin  string   `db:"origin"` // "c" if created by CREATE INDEX, "u" by a UNIQUE constraint or "pk" by a PRIMARY KEY.
+	Partial bool     `db:"partial"`
+	Columns []string // Indexed columns, empty for expressions.
+}
+
+// A ForeignKey describes a foreign key of a table.
+type ForeignKey struct {
+	Table    string   // Referenced table.
+	From     []string // Columns of the table.
+	To       []string // Columns of the referenced table, empty if its primary key.
+	OnUpdate string
+	OnDelete string
+}
+
+// A Trigger describes a trigger.
+type Trigger struct {
+	Name  string `db:"name"`
+	Table string `db:"tbl_name"`
+	SQL   string `db:"sql"`
+}
+
+// Schema returns the schema of the main database, ordered by name. Tables
+// used internally by SQLite are not included.
+func (db *DB) Schema(ctx context.Context) (*Schema, error) {
+	var s Schema
+	err := db.DoReadTx(ctx, func(ctx context.Context, tx *ReadTx) (err error) {
+		s.Tables, err = QueryAll[Table](ctx, tx, `select name, sql from sqlite_schema
+			where type = 'table' and name not like 'sqlite_%' order by name`)
+		if err != nil {
+			return fmt.Errorf("query tables: %w", err)
+		}
+		for i := range s.Tables {
+			if err := s.Tables[i].introspect(ctx, tx); err != nil {
+				return err
+			}
+		}
+		s.Triggers, err = QueryAll[Trigger](ctx, tx, `select name, tbl_name, sql from sqlite_schema
+			where type = 'trigger' order by name`)
+		if err != nil {
+			return fmt.Errorf("query triggers: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (t *Table) introspect(ctx context.Context, tx *ReadTx) (err error) {
+	t.Columns, err = QueryAll[Column](ctx, tx, `select name, type, "notnull", dflt_value, pk
+		from pragma_table_info(?) order by cid`, t.Name)
+	if err != nil {
+		return fmt.Errorf("query columns of %s: %w", t.Name, err)
+	}
+	t.Indexes, err = QueryAll[Index](ctx, tx, `select name, "unique", origin, partial
+		from pragma_index_list(?) order by name`, t.Name)
+	if err != nil {
+		return fmt.Errorf("query indexes of %s: %w", t.Name, err)
+	}
+	for i, idx := range t.Indexes {
+		t.Indexes[i].Columns, err = QueryAll[string](ctx, tx, `select name from pragma_index_info(?)
+			where name is not null order by seqno`, idx.Name)
+		if err != nil {
+			return fmt.Errorf("query columns of index %s: %w", idx.Name, err)
+		}
+	}
+	type fkRow struct {
+		ID       int            `db:"id"`
+		Table    string         `db:"table"`
+		From     string         `db:"from"`
+		To       sql.NullString `db:"to"`
+		OnUpdate string         `db:"on_update"`
+		OnDelete string         `db:"on_delete"`
+	}
+	rows, err := QueryAll[fkRow](ctx, tx, `select id, "table", "from", "to", on_update, on_delete
+		from pragma_foreign_key_list(?) order by id, seq`, t.Name)
+	if err != nil {
+		return fmt.Errorf("query foreign keys of %s: %w", t.Name, err)
+	}
+	t.ForeignKeys = nil
+	for i, r := range rows {
+		if i == 0 || r.ID != rows[i-1].ID {
+			t.ForeignKeys = append(t.ForeignKeys, ForeignKey{Table: r.Table, OnUpdate: r.OnUpdate, OnDelete: r.OnDelete})
+		}
+		fk := &t.ForeignKeys[len(t.ForeignKeys)-1]
+		fk.From = append(fk.From, r.From)
+		if r.To.Valid {
+			fk.To = append(fk.To, r.To.String)
+		}
+	}
+	return nil
+}
+
+// A SchemaChange is a difference between two schemas.
+type SchemaChange struct {
+	Kind   string // "table", "column", "index", "foreign key" or "trigger".
+	Table  string
+	Name   string // Definition of a foreign key, empty for tables.
+	Change string // "added", "removed" or "changed".
+}
+
+func (c SchemaChange) String() string {
+	name := c.Table
+	if c.Name != "" {
+		name += "." + c.Name
+	}
+	return fmt.Sprintf("%s %s %s", c.Kind, name, c.Change)
+}
+
+// DiffSchema returns the changes that turn schema from into schema to.
+func DiffSchema(from, to *Schema) []SchemaChange {
+	var changes []SchemaChange
+	diff(from.Tables, to.Tables, func(t Table) string { return t.Name }, func(a, b *Table, change string) {
+		if change != "changed" {
+			changes = append(changes, SchemaChange{Kind: "table", Table: tableName(a, b), Change: change})
+			return
+		}
+		n := len(changes)
+		add := func(kind string) func(name, change string) {
+			return func(name, change string) {
+				changes = append(changes, SchemaChange{Kind: kind, Table: a.Name, Name: name, Change: change})
+			}
+		}
+		diffNamed(a.Columns, b.Columns, func(c Column) string { return c.Name }, add("column"))
+		diffNamed(a.Indexes, b.Indexes, func(i Index) string { return i.Name }, add("index"))
+		diffNamed(a.ForeignKeys, b.ForeignKeys, func(fk ForeignKey) string {
+			return fmt.Sprintf("(%s) references %s (%s)", strings.Join(fk.From, ", "), fk.Table, strings.Join(fk.To, ", "))
+		}, add("foreign key"))
+		if len(changes) == n {
+			// such as a CHECK constraint
+			changes = append(changes, SchemaChange{Kind: "table", Table: a.Name, Change: change})
+		}
+	})
+	diff(from.Triggers, to.Triggers, func(t Trigger) string { return t.Name }, func(a, b *Trigger, change string) {
+		t := a
+		if t == nil {
+			t = b
+		}
+		changes = append(changes, SchemaChange{Kind: "trigger", Table: t.Table, Name: t.Name, Change: change})
+	})
+	return changes
+}
+
+func tableName(a, b *Table) string {
+	if a != nil {
+		return a.Name
+	}
+	return b.Name
+}
+
+// diff calls f for each value of from that is removed or changed in to, then
+// each value added in to. Values are matched by key.
+func diff[T any](from, to []T, key func(T) string, f func(a, b *T, change string)) {
+	for i := range from {
+		j := slices.IndexFunc(to, func(v T) bool { return key(v) == key(from[i]) })
+		switch {
+		case j < 0:
+			f(&from[i], nil, "removed")
+		case !reflect.DeepEqual(from[i], to[j]):
+			f(&from[i], &to[j], "changed")
+		}
+	}
+	for j := range to {
+		if !slices.ContainsFunc(from, func(v T) bool { return key(v) == key(to[j]) }) {
+			f(nil, &to[j], "added")
+		}
+	}
+}
+
+// diffNamed is diff reporting only the key of each value.
+func diffNamed[T any](from, to []T, key func(T) string, f func(name, change string)) {
+	diff(from, to, key, func(a, b *T, change string) {
+		if a == nil {
+			a = b
+		}
+		f(key(*a), change)
+	})
+}
@@ -0,0 +1,83 @@
+package sql3_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_DB_Schema(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		_, err := db.Exec(context.TODO(), `create table notes (
+			id integer primary key,
+			test_id text not null references tests (id) on delete cascade,
+			body text default 'empty'
+		)`)
+		is.NoErr(err) // (sql3.DB).Exec
+		_, err = db.Exec(context.TODO(), `create trigger notes_ai after insert on notes begin select 1; end`)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		s, err := db.Schema(context.TODO())
+		is.NoErr(err) // (sql3.DB).Schema
+
+		var names []string
+		for _, t := range s.Tables {
+			names = append(names, t.Name)
+		}
+		is.Equal(names, []string{"migration_checksums", "migrations", "notes", "tests"})
+
+		notes := s.Tables[2]
+		is.Equal(notes.Columns, []Column{
+			{Name: "id", Type: "INTEGER", PK: 1},
+			{Name: "test_id", Type: "TEXT", NotNull: true},
+			{Name: "body", Type: "TEXT", Default: sql.NullString{String: "'empty'", Valid: true}},
+		})
+		is.Equal(notes.ForeignKeys, []ForeignKey{
+			{Table: "tests", From: []string{"test_id"}, To: []string{"id"}, OnUpdate: "NO ACTION", OnDelete: "CASCADE"},
+		})
+
+		tests := s.Tables[3]
+		is.Equal(len(tests.Indexes), 2)
+		is.Equal(tests.Indexes[1], Index{Name: "tests_counter", Origin: "c", Columns: []string{"counter"}})
+
+		is.Equal(s.Triggers, []Trigger{{Name: "notes_ai", Table: "notes", SQL: "CREATE TRIGGER notes_ai after insert on notes begin select 1; end"}})
+	}))
+}
+
+func Test_DiffSchema(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		from, err := db.Schema(context.TODO())
+		is.NoErr(err) // (sql3.DB).Schema
+
+		for _, query := range []string{
+			`create table notes (id integer primary key)`,
+			`alter table tests add column note_id int references notes (id)`,
+			`drop index tests_counter`,
+		} {
+			_, err := db.Exec(context.TODO(), query)
+			is.NoErr(err) // (sql3.DB).Exec
+		}
+
+		to, err := db.Schema(context.TODO())
+		is.NoErr(err) // (sql3.DB).Schema
+
+		var changes []string
+		for _, c := range DiffSchema(from, to) {
+			changes = append(changes, c.String())
+		}
+		is.Equal(changes, []string{
+			"column tests.note_id added",
+			"index tests.tests_counter removed",
+			"foreign key tests.(note_id) references notes (id) added",
+			"table notes added",
+		})
+		is.Equal(len(DiffSchema(to, to)), 0)
+	}))
+}
@@ -0,0 +1,43 @@
+// Package sql3test provides utilities for testing code that uses [sql3.DB].
+package sql3test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.adoublef.dev/sdk/database/sql3"
+)
+
+type template struct {
+	once sync.Once
+	db   *sql3.DB
+	err  error
+}
+
+var templates sync.Map // map[*sql3.FS]*template
+
+// Up returns a uniquely named in-memory [sql3.DB] with the migrations of fsys
+// applied. The migrations are only run once per fsys, to a template database
+// which is then cloned for each test. The options are applied to both the
+// template and the clone, the template uses the options of the first call.
+// See [sql3.WithMemory] for how reads wait for an open write transaction.
+//
+// The database is closed when the test and its subtests complete.
+func Up(t testing.TB, fsys *sql3.FS, opts ...sql3.Option) *sql3.DB {
+	t.Helper()
+	v, _ := templates.LoadOrStore(fsys, &template{})
+	tmpl := v.(*template)
+	tmpl.once.Do(func() {
+		tmpl.db, tmpl.err = fsys.Up(context.Background(), "", append(opts, sql3.WithMemory())...)
+	})
+	if tmpl.err != nil {
+		t.Fatalf("sql3test: migrate template: %v", tmpl.err)
+	}
+	db, err := tmpl.db.Clone(context.Background(), opts...)
+	if err != nil {
+		t.Fatalf("sql3test: clone template: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
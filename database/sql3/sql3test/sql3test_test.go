@@ -0,0 +1,54 @@
+package sql3test_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"go.adoublef.dev/is"
+	"go.adoublef.dev/sdk/database/sql3"
+	. "go.adoublef.dev/sdk/database/sql3/sql3test"
+)
+
+var sqlFS, _ = sql3.NewFS(fstest.MapFS{
+	"0.up.sql": {Data: []byte(`create table tests (id text not null primary key, counter int not null) strict;`)},
+}, "")
+
+func Test_Up(t *testing.T) {
+	for _, name := range []string{"A", "B"} {
+		t.Run(name, func(t *testing.T) {
+			is := is.NewRelaxed(t)
+
+			db := Up(t, sqlFS)
+
+			_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "a", 1)
+			is.NoErr(err) // (sql3.DB).Exec
+
+			var n int
+			err = db.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+			is.NoErr(err)  // (sql3.DB).QueryRow
+			is.Equal(n, 1) // clones do not share data
+		})
+	}
+}
+
+func Test_Up_Tx(t *testing.T) {
+	is := is.NewRelaxed(t)
+
+	db := Up(t, sqlFS)
+
+	var n int
+	err := db.DoTx(context.TODO(), func(ctx context.Context, tx *sql3.Tx) error {
+		if _, err := tx.Exec(ctx, `insert into tests (id, counter) values (?, ?)`, "a", 1); err != nil {
+			return err
+		}
+		// reads on the read pool would wait for the transaction
+		return tx.QueryRow(ctx, `select count(*) from tests`).Scan(&n)
+	})
+	is.NoErr(err)  // (sql3.DB).DoTx
+	is.Equal(n, 1) // the transaction sees its own writes
+
+	err = db.QueryRow(context.TODO(), `select count(*) from tests`).Scan(&n)
+	is.NoErr(err) // (sql3.DB).QueryRow
+	is.Equal(n, 1)
+}
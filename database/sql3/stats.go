@@ -0,0 +1,149 @@
+package sql3
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Stats contains statistics for both pools of a [DB] and the database itself.
+type Stats struct {
+	Read  sql.DBStats // Statistics for the read pool.
+	Write sql.DBStats // Statistics for the write pool.
+
+	PageSize      int64 // Size of a page in bytes.
+	PageCount     int64 // Number of pages in the database file.
+	FreelistCount int64 // Number of unused pages in the database file.
+
+	WALSize int64 // Size of the WAL file in bytes.
+	// Number of frames the WAL file can hold, derived from WALSize. Only a
+	// TRUNCATE checkpoint shrinks the file so after a PASSIVE or FULL
+	// checkpoint this includes frames that have already been checkpointed.
+	// LastCheckpoint reports the frames written back to the database.
+	WALFrames int64
+
+	LastCheckpoint CheckpointResult // Result of the last call to [DB.Checkpoint].
+}
+
+// Stats returns statistics for the database.
+func (db *DB) Stats(ctx context.Context) (Stats, error) {
+	s := Stats{Read: db.rc.Stats(), Write: db.wc.Stats()}
+	err := db.rc.QueryRowContext(ctx, `select * from pragma_page_size(), pragma_page_count(), pragma_freelist_count()`).
+		Scan(&s.PageSize, &s.PageCount, &s.FreelistCount)
+	if err != nil {
+		return Stats{}, fmt.Errorf("query page statistics: %w", err)
+	}
+	if s.WALSize, err = db.walSize(); err != nil {
+		return Stats{}, err
+	}
+	// the WAL starts with a 32 byte header and each frame has a 24 byte header
+	if s.WALSize > 32 && s.PageSize > 0 {
+		s.WALFrames = (s.WALSize - 32) / (s.PageSize + 24)
+	}
+	if c := db.checkpoint.Load(); c != nil {
+		s.LastCheckpoint = *c
+	}
+	return s, nil
+}
+
+// walSize returns the size of the WAL file in bytes.
+func (db *DB) walSize() (int64, error) {
+	if db.memory != nil {
+		return 0, nil
+	}
+	fi, err := os.Stat(db.filename + "-wal")
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("stat WAL file: %w", err)
+	}
+	return fi.Size(), nil
+}
+
+// walBacklog returns the size in bytes of the frames in the WAL which have
+// not been checkpointed. It reads the WAL index, see
+// https://sqlite.org/walformat.html, which is stored in native byte order.
+func (db *DB) walBacklog() (int64, error) {
+	if db.memory != nil {
+		return 0, nil
+	}
+	f, err := os.Open(db.filename + "-shm")
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("open WAL index: %w", err)
+	}
+	defer f.Close()
+	// the header is followed by a copy of itself and then the checkpoint
+	// information
+	var b [100]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		return 0, fmt.Errorf("read WAL index: %w", err)
+	}
+	pageSize := int64(binary.NativeEndian.Uint16(b[14:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	mxFrame := int64(binary.NativeEndian.Uint32(b[16:]))
+	nBackfill := int64(binary.NativeEndian.Uint32(b[96:]))
+	if mxFrame <= nBackfill {
+		return 0, nil
+	}
+	// each frame has a 24 byte header
+	return (mxFrame - nBackfill) * (pageSize + 24), nil
+}
+
+// Ping verifies that both pools can connect to the database.
+func (db *DB) Ping(ctx context.Context) error {
+	if err := db.rc.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping read pool: %w", err)
+	}
+	if err := db.wc.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping write pool: %w", err)
+	}
+	return nil
+}
+
+// CheckpointMode is the mode of a WAL checkpoint.
+type CheckpointMode string
+
+// Checkpoint modes, see https://www.sqlite.org/pragma.html#pragma_wal_checkpoint.
+const (
+	CheckpointPassive  CheckpointMode = "PASSIVE"
+	CheckpointFull     CheckpointMode = "FULL"
+	CheckpointRestart  CheckpointMode = "RESTART"
+	CheckpointTruncate CheckpointMode = "TRUNCATE"
+)
+
+// CheckpointResult is the result of a WAL checkpoint.
+type CheckpointResult struct {
+	Mode         CheckpointMode
+	Time         time.Time
+	Busy         bool // The checkpoint could not complete.
+	Log          int  // Number of frames in the WAL.
+	Checkpointed int  // Number of frames written back to the database.
+}
+
+// Checkpoint runs a WAL checkpoint in the given mode.
+func (db *DB) Checkpoint(ctx context.Context, mode CheckpointMode) (CheckpointResult, error) {
+	switch mode {
+	case CheckpointPassive, CheckpointFull, CheckpointRestart, CheckpointTruncate:
+	default:
+		return CheckpointResult{}, fmt.Errorf("unknown checkpoint mode %q", mode)
+	}
+	c := CheckpointResult{Mode: mode}
+	err := db.wc.QueryRowContext(ctx, `pragma wal_checkpoint(`+string(mode)+`)`).Scan(&c.Busy, &c.Log, &c.Checkpointed)
+	if err != nil {
+		return CheckpointResult{}, fmt.Errorf("checkpoint: %w", err)
+	}
+	c.Time = time.Now()
+	db.checkpoint.Store(&c)
+	return c, nil
+}
@@ -0,0 +1,35 @@
+package sql3_test
+
+import (
+	"context"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_DB_Stats(t *testing.T) {
+	t.Run("OK", testRoundTrip(func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		is.NoErr(db.Ping(context.TODO())) // (sql3.DB).Ping
+
+		_, err := db.Exec(context.TODO(), `insert into tests (id, counter) values (?, ?)`, "a", 1)
+		is.NoErr(err) // (sql3.DB).Exec
+
+		s, err := db.Stats(context.TODO())
+		is.NoErr(err) // (sql3.DB).Stats
+		is.True(s.PageCount > 0)
+		is.True(s.WALFrames > 0)
+		is.True(s.LastCheckpoint.Time.IsZero())
+
+		c, err := db.Checkpoint(context.TODO(), CheckpointTruncate)
+		is.NoErr(err) // (sql3.DB).Checkpoint
+		is.True(!c.Busy)
+
+		s, err = db.Stats(context.TODO())
+		is.NoErr(err) // (sql3.DB).Stats
+		is.Equal(s.WALFrames, int64(0))
+		is.Equal(s.LastCheckpoint, c)
+	}))
+}
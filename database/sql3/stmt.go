@@ -0,0 +1,150 @@
+package sql3
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// StmtCacheStats contains statistics for a prepared statement cache.
+type StmtCacheStats struct {
+	Len       int    // Number of cached statements.
+	Hits      uint64 // Number of queries that used a cached statement.
+	Misses    uint64 // Number of queries that prepared a new statement.
+	Evictions uint64 // Number of statements closed to make room for another.
+}
+
+// stmtCache is a least recently used cache of prepared statements keyed by
+// query text.
+type stmtCache struct {
+	db    *sql.DB
+	size  int
+	mu    sync.Mutex
+	ll    *list.List // of *stmtEntry, most recently used at the front
+	m     map[string]*list.Element
+	stats StmtCacheStats
+}
+
+type stmtEntry struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int  // number of callers using the statement
+	evicted bool // close once refs drops to zero
+}
+
+func newStmtCache(db *sql.DB, size int) *stmtCache {
+	return &stmtCache{db: db, size: size, ll: list.New(), m: make(map[string]*list.Element)}
+}
+
+// lookup returns the cached statement for query. The release function must be
+// called once the statement is no longer used.
+func (c *stmtCache) lookup(query string) (*sql.Stmt, func(), bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.m[query]
+	if !ok {
+		c.stats.Misses++
+		return nil, nil, false
+	}
+	c.ll.MoveToFront(e)
+	c.stats.Hits++
+	se := e.Value.(*stmtEntry)
+	se.refs++
+	return se.stmt, func() { c.release(se) }, true
+}
+
+// prepare returns the cached statement for query, preparing it on a miss. The
+// release function must be called once the statement is no longer used.
+func (c *stmtCache) prepare(ctx context.Context, query string) (*sql.Stmt, func(), error) {
+	if stmt, release, ok := c.lookup(query); ok {
+		return stmt, release, nil
+	}
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.m[query]; ok {
+		// prepared concurrently by another caller
+		stmt.Close()
+		c.ll.MoveToFront(e)
+		se := e.Value.(*stmtEntry)
+		se.refs++
+		return se.stmt, func() { c.release(se) }, nil
+	}
+	se := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	c.m[query] = c.ll.PushFront(se)
+	for c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		old := e.Value.(*stmtEntry)
+		delete(c.m, old.query)
+		c.stats.Evictions++
+		if old.evicted = true; old.refs == 0 {
+			old.stmt.Close()
+		}
+	}
+	return stmt, func() { c.release(se) }, nil
+}
+
+func (c *stmtCache) release(se *stmtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if se.refs--; se.refs == 0 && se.evicted {
+		se.stmt.Close()
+	}
+}
+
+// Stats returns the statistics for the cache.
+func (c *stmtCache) Stats() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Len = c.ll.Len()
+	return s
+}
+
+// Close closes every cached statement.
+func (c *stmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for e := c.ll.Front(); e != nil; e = e.Next() {
+		// statements in use are closed once their queries finish
+		errs = append(errs, e.Value.(*stmtEntry).stmt.Close())
+	}
+	c.ll.Init()
+	clear(c.m)
+	return errors.Join(errs...)
+}
+
+// txStmt returns the cached statement for query bound to tx. A statement is
+// not prepared on a miss as the pool may have no free connections while tx is
+// open.
+func (c *stmtCache) txStmt(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, bool) {
+	if c == nil {
+		return nil, false
+	}
+	stmt, release, ok := c.lookup(query)
+	if !ok {
+		return nil, false
+	}
+	defer release()
+	return tx.StmtContext(ctx, stmt), true
+}
+
+// StmtCacheStats returns the statistics of the prepared statement caches for
+// the read and write pools. They are zero if [WithStmtCache] was not used.
+func (db *DB) StmtCacheStats() (read, write StmtCacheStats) {
+	if db.rcache != nil {
+		read = db.rcache.Stats()
+	}
+	if db.wcache != nil {
+		write = db.wcache.Stats()
+	}
+	return read, write
+}
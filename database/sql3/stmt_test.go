@@ -0,0 +1,43 @@
+package sql3_test
+
+import (
+	"context"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+func Test_WithStmtCache(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := sqlFS.Up(context.TODO(), testFilename(t, "test.db"), WithStmtCache(2))
+		is.NoErr(err) // (sql3.FS).Up
+		t.Cleanup(func() { db.Close() })
+
+		const insert = `insert into tests (id, counter) values (?, ?)`
+		for i := range 3 {
+			_, err := db.Exec(context.TODO(), insert, i, i)
+			is.NoErr(err) // (sql3.DB).Exec
+		}
+		_, write := db.StmtCacheStats()
+		is.Equal(write, StmtCacheStats{Len: 1, Hits: 2, Misses: 1})
+
+		err = db.DoTx(context.TODO(), func(ctx context.Context, tx *Tx) error {
+			_, err := tx.Exec(ctx, insert, "a", 4)
+			return err
+		})
+		is.NoErr(err) // (sql3.DB).DoTx
+		_, write = db.StmtCacheStats()
+		is.Equal(write.Hits, uint64(3))
+
+		for _, q := range []string{`select 1`, `select 2`, `select 3`, `select 1`} {
+			var n int
+			err := db.QueryRow(context.TODO(), q).Scan(&n)
+			is.NoErr(err) // (sql3.DB).QueryRow
+		}
+		read, _ := db.StmtCacheStats()
+		is.Equal(read, StmtCacheStats{Len: 2, Misses: 4, Evictions: 2})
+	})
+}
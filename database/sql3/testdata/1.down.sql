@@ -0,0 +1 @@
+drop index tests_counter;
@@ -0,0 +1 @@
+create index tests_counter on tests (counter);
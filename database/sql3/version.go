@@ -0,0 +1,83 @@
+package sql3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrConflict is matched by a [ConflictError] using [errors.Is].
+var ErrConflict = errors.New("sql3: version conflict")
+
+// A ConflictError is returned by [UpdateVersion] when the row has been
+// changed or deleted since it was read.
+type ConflictError struct {
+	Table   string
+	Version int64 // Version the update expected.
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("sql3: version conflict updating %s at version %d", e.Table, e.Version)
+}
+
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// PreconditionFailed reports that the conflict is a failed precondition, so
+// that httputil.PreconditionFailed responds with 412 Precondition Failed.
+func (e *ConflictError) PreconditionFailed() bool { return true }
+
+// UpdateVersion sets the columns of the row in table with the same key and
+// version as v, then increments the version of both the row and v. The
+// version is the integer field whose "db" tag has the "version" option, e.g.
+// `db:"version,version"`. If no row matches a [ConflictError] is returned.
+func UpdateVersion[T any](ctx context.Context, e Executor, table string, v *T) error {
+	info, err := columnsOf[T]()
+	if err != nil {
+		return err
+	}
+	keys, rest := splitKeys(info.columns)
+	if len(keys) == 0 {
+		return fmt.Errorf("sql3: no key columns for %v", reflect.TypeFor[T]())
+	}
+	var (
+		version column
+		set     []column
+	)
+	for _, c := range rest {
+		if c.version {
+			version = c
+			continue
+		}
+		set = append(set, c)
+	}
+	if version.index == nil {
+		return fmt.Errorf("sql3: no version column for %v", reflect.TypeFor[T]())
+	}
+	field := reflect.ValueOf(v).Elem().FieldByIndex(version.index)
+	if !field.CanInt() {
+		return fmt.Errorf("sql3: version column %s of %v is not an integer", version.name, reflect.TypeFor[T]())
+	}
+	name := quoteIdent(version.name)
+	assign := name + " = " + name + " + 1"
+	if len(set) > 0 {
+		assign = columnList(set, "%s = ?") + ", " + assign
+	}
+	query := fmt.Sprintf("update %s set %s where %s and %s = ?",
+		quoteTable(table), assign, strings.ReplaceAll(columnList(keys, "%s = ?"), ", ", " and "), name)
+	args := append(values(*v, set), values(*v, keys)...)
+	res, err := e.Exec(ctx, query, append(args, field.Int())...)
+	if err != nil {
+		return fmt.Errorf("update %s: %w", table, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update %s: %w", table, err)
+	}
+	if n == 0 {
+		return &ConflictError{Table: table, Version: field.Int()}
+	}
+	field.SetInt(field.Int() + 1)
+	return nil
+}
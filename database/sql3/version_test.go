@@ -0,0 +1,82 @@
+package sql3_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/database/sql3"
+)
+
+type testDoc struct {
+	ID      int64  `db:"id,key,auto"`
+	Body    string `db:"body"`
+	Version int64  `db:"version,version"`
+}
+
+const docsTable = `create table docs (id integer primary key, body text not null, version int not null)`
+
+func Test_UpdateVersion(t *testing.T) {
+	t.Run("OK", testTable(docsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		id, err := Insert(context.TODO(), db, "docs", testDoc{Body: "a", Version: 1})
+		is.NoErr(err) // sql3.Insert
+
+		doc := testDoc{ID: id, Body: "b", Version: 1}
+		err = UpdateVersion(context.TODO(), db, "docs", &doc)
+		is.NoErr(err) // sql3.UpdateVersion
+		is.Equal(doc.Version, int64(2))
+
+		got, err := QueryOne[testDoc](context.TODO(), db, `select * from docs where id = ?`, id)
+		is.NoErr(err) // sql3.QueryOne
+		is.Equal(got, doc)
+	}))
+
+	t.Run("ErrConflict", testTable(docsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		id, err := Insert(context.TODO(), db, "docs", testDoc{Body: "a", Version: 1})
+		is.NoErr(err) // sql3.Insert
+
+		first, second := testDoc{ID: id, Body: "b", Version: 1}, testDoc{ID: id, Body: "c", Version: 1}
+		err = UpdateVersion(context.TODO(), db, "docs", &first)
+		is.NoErr(err) // sql3.UpdateVersion
+
+		err = UpdateVersion(context.TODO(), db, "docs", &second)
+		is.True(errors.Is(err, ErrConflict)) // sql3.UpdateVersion
+		is.Equal(second.Version, int64(1))
+
+		var ce *ConflictError
+		is.True(errors.As(err, &ce))
+		is.Equal(ce.Version, int64(1))
+	}))
+
+	t.Run("Schema", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		db, err := Open(testFilename(t, "test.db"), WithAttach("archive", testFilename(t, "archive.db")))
+		is.NoErr(err) // sql3.Open
+		t.Cleanup(func() { db.Close() })
+
+		_, err = db.Exec(context.TODO(), strings.Replace(docsTable, "docs", "archive.docs", 1))
+		is.NoErr(err) // (sql3.DB).Exec
+
+		id, err := Insert(context.TODO(), db, "archive.docs", testDoc{Body: "a", Version: 1})
+		is.NoErr(err) // sql3.Insert
+
+		doc := testDoc{ID: id, Body: "b", Version: 1}
+		err = UpdateVersion(context.TODO(), db, "archive.docs", &doc)
+		is.NoErr(err) // sql3.UpdateVersion
+		is.Equal(doc.Version, int64(2))
+	})
+
+	t.Run("NoVersion", testTable(itemsTable, func(db *DB) {
+		is := is.NewRelaxed(t)
+
+		err := UpdateVersion(context.TODO(), db, "items", &testItem{ID: 1})
+		is.True(err != nil) // sql3.UpdateVersion
+	}))
+}
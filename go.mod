@@ -1,11 +1,12 @@
 module go.adoublef.dev/sdk
 
-go 1.22
+go 1.23
 
 require (
 	github.com/maragudk/migrate v0.4.3
 	github.com/mattn/go-sqlite3 v1.14.22
 	go.adoublef.dev/is v0.1.2
+	golang.org/x/crypto v0.19.0
 	golang.org/x/sync v0.7.0
 )
 
@@ -14,6 +15,5 @@ require github.com/matryer/is v1.4.1 // indirect
 require (
 	github.com/go-sql-driver/mysql v1.7.1 // indirect
 	github.com/google/uuid v1.6.0
-	golang.org/x/crypto v0.19.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 )
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"go.adoublef.dev/sdk/net/http/httputil/hlog"
+)
+
+// HealthHandler returns a [http.Handler] suitable for readiness probes. It
+// responds with 200 if every check passes, otherwise 503. The error of the
+// first check that failed is logged rather than written to the response.
+func HealthHandler(checks ...func(context.Context) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		for _, check := range checks {
+			if err := check(ctx); err != nil {
+				hlog.Logger(r).Error("health check failed", "err", err)
+				code := http.StatusServiceUnavailable
+				http.Error(w, http.StatusText(code), code)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,36 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/net/http"
+)
+
+func Test_HealthHandler(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("secret: connection refused") }
+
+	for name, tc := range map[string]struct {
+		checks []func(context.Context) error
+		code   int
+	}{
+		"OK":   {[]func(context.Context) error{ok, ok}, http.StatusOK},
+		"None": {nil, http.StatusOK},
+		"Fail": {[]func(context.Context) error{ok, fail}, http.StatusServiceUnavailable},
+	} {
+		t.Run(name, func(t *testing.T) {
+			is := is.NewRelaxed(t)
+
+			w := httptest.NewRecorder()
+			HealthHandler(tc.checks...).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			is.Equal(w.Code, tc.code)
+			is.True(!strings.Contains(w.Body.String(), "secret")) // error is not leaked
+		})
+	}
+}
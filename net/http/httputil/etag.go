@@ -0,0 +1,37 @@
+package httputil
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag returns a strong entity tag for a resource at version.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// IfMatch returns the version given by the If-Match header of r, as formatted
+// by [ETag]. It returns false if the header is missing, or does not name a
+// single version such as a weak tag or "*".
+func IfMatch(r *http.Request) (int64, bool) {
+	tag := strings.TrimSpace(r.Header.Get("If-Match"))
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	return version, err == nil
+}
+
+// PreconditionFailed responds with 412 Precondition Failed and returns true if
+// err, or any error it wraps, has a PreconditionFailed method that returns
+// true, such as the conflict errors of sql3.UpdateVersion.
+func PreconditionFailed(w http.ResponseWriter, err error) bool {
+	var pe interface{ PreconditionFailed() bool }
+	if !errors.As(err, &pe) || !pe.PreconditionFailed() {
+		return false
+	}
+	http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+	return true
+}
@@ -0,0 +1,58 @@
+package httputil_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.adoublef.dev/is"
+	. "go.adoublef.dev/sdk/net/http/httputil"
+)
+
+type testConflict struct{}
+
+func (testConflict) Error() string            { return "conflict" }
+func (testConflict) PreconditionFailed() bool { return true }
+
+func Test_IfMatch(t *testing.T) {
+	for _, tc := range []struct {
+		header  string
+		version int64
+		ok      bool
+	}{
+		{ETag(42), 42, true},
+		{"", 0, false},
+		{"*", 0, false},
+		{`W/"42"`, 0, false},
+		{`"abc"`, 0, false},
+	} {
+		t.Run(tc.header, func(t *testing.T) {
+			is := is.NewRelaxed(t)
+
+			r := httptest.NewRequest(http.MethodPut, "/", nil)
+			r.Header.Set("If-Match", tc.header)
+			version, ok := IfMatch(r)
+			is.Equal(ok, tc.ok)
+			is.Equal(version, tc.version)
+		})
+	}
+}
+
+func Test_PreconditionFailed(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		w := httptest.NewRecorder()
+		is.True(PreconditionFailed(w, fmt.Errorf("update: %w", testConflict{})))
+		is.Equal(w.Code, http.StatusPreconditionFailed)
+	})
+
+	t.Run("Other", func(t *testing.T) {
+		is := is.NewRelaxed(t)
+
+		w := httptest.NewRecorder()
+		is.True(!PreconditionFailed(w, fmt.Errorf("other")))
+		is.Equal(w.Code, http.StatusOK)
+	})
+}
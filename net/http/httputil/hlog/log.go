@@ -43,7 +43,12 @@ func LogHandler(h http.Handler, sl *slog.Logger) http.Handler {
 
 // Logger returns the in-context Logger for a request.
 func Logger(r *http.Request) *slog.Logger {
-	entry, ok := r.Context().Value(LoggerContextKey).(*logger)
+	return FromContext(r.Context())
+}
+
+// FromContext returns the Logger stored in ctx by [LogHandler].
+func FromContext(ctx context.Context) *slog.Logger {
+	entry, ok := ctx.Value(LoggerContextKey).(*logger)
 	if !ok || entry == nil {
 		opts := &slog.HandlerOptions{
 			AddSource: true,